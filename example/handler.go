@@ -10,50 +10,41 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/flash520/pusher/pkg/pusher"
 )
 
-type Car struct {
-	ctx        context.Context
-	cancelFunc context.CancelFunc
-	Number     int `json:"number"`
-}
-
-func (c *Car) Name() string {
-	return "Car"
-}
-
-func (c *Car) Handle(data pusher.Data) {
-	logrus.Infof("%s 开始数据处理, 消息ID: %s", c.Name(), data.ID())
-}
-
-func (c *Car) TopicView(data pusher.Data, user pusher.User) {
-	if user.First() {
-		message := pusher.NewMessage(c.Name(), "这是首次加载数据", user.First())
-		user.Write(message)
-		return
-	}
-	c.Number++
-	// err := fmt.Errorf("%s", "错啦~")
-	// message := pusher.NewMessage(c.Name(), err, user.First())
-	// user.Write(message)
-
-	message := pusher.NewMessage(c.Name(), data.Raw(), user.First())
-	user.Write(message)
-}
-
-func (c *Car) SetContext(ctx context.Context, cancelFunc context.CancelFunc) {
-	c.ctx = ctx
-	c.cancelFunc = cancelFunc
-}
-
-func (c *Car) Clone() pusher.Handler {
-	return &Car{}
-}
-
-func init() {
-	hub.TopicRegister(&Car{})
+var Car = &pusher.TypedHandler[int]{
+	Topic: "Car",
+	Process: func(ctx context.Context, number int) {
+		logrus.Infof("Car 开始数据处理, 编号: %d", number)
+	},
+	Snapshot: func(ctx context.Context, user pusher.User) (int, error) {
+		return 0, nil
+	},
+	View: func(ctx context.Context, number int, user pusher.User) (interface{}, bool) {
+		return map[string]int{"number": number}, true
+	},
+	// 每个订阅者独立的定时任务，取消订阅或断开连接时随 ctx 退出
+	Subscribed: func(ctx context.Context, user pusher.User, params json.RawMessage) {
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					logrus.Infof("Car 订阅者 %v 在线", user.User())
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	},
+	Unsubscribed: func(user pusher.User) {
+		logrus.Infof("Car 订阅者 %v 已取消订阅", user.User())
+	},
 }
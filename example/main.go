@@ -9,15 +9,21 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 
+	"github.com/flash520/pusher/pkg/auth"
 	"github.com/flash520/pusher/pkg/connector"
+	"github.com/flash520/pusher/pkg/pipeline"
 	"github.com/flash520/pusher/pkg/pusher"
+	"github.com/flash520/pusher/pkg/script"
 )
 
 var hub *pusher.Hub
@@ -26,11 +32,61 @@ func main() {
 	app := gin.Default()
 
 	hub = pusher.NewHub()
+	hub.TopicRegister(Car)
+	// 记录处理耗时超过 100ms 的数据
+	hub.Use(func(next pusher.HandleFunc) pusher.HandleFunc {
+		return func(handler pusher.Handler, msg pusher.Data) {
+			start := time.Now()
+			next(handler, msg)
+			if elapsed := time.Since(start); elapsed > time.Millisecond*100 {
+				logrus.Warnf("%s Handle %s Slow: %s", handler.Name(), msg.ID(), elapsed)
+			}
+		}
+	})
+	// 设置 PUSHER_JWT_SECRET 后开启 JWT 鉴权，令牌可通过 ?token=、Authorization 头或 Sec-WebSocket-Protocol 传递
+	if secret := os.Getenv("PUSHER_JWT_SECRET"); secret != "" {
+		keys := auth.NewKeySet()
+		keys.AddHMAC("", []byte(secret))
+		hub.SetAuthenticator(auth.NewJWTAuthenticator(auth.JWTConfig{Keys: keys}))
+		upgrader.Subprotocols = []string{"access_token"}
+	}
 	// hub.SetHandleRequest(func(data []byte, client pusher.Client) {
 	// 	response := pusher.NewResponse("error", string(data))
 	// 	client.SendMessage(response)
 	// })
+	hub.SetLimits(pusher.Limits{
+		AllowedOrigins:        []string{"localhost:8080", "*.example.com"},
+		MaxConnections:        10000,
+		MaxConnectionsPerIP:   50,
+		MaxConnectionsPerUser: 10,
+		MaxTopicsPerClient:    20,
+		SubscribeRate:         2,
+		SubscribeBurst:        10,
+		RequestRate:           20,
+		RequestBurst:          50,
+		MaxMalformed:          10,
+		BanDuration:           time.Minute * 5,
+	})
+	hub.RegisterMethod("ackAlarm", pusher.TypedMethod(AckAlarm), pusher.WithMethodTimeout(time.Second*3))
 	app.GET("/ws/connect", Connect)
+	app.POST("/notify/:user", Notify)
+
+	// kafka 消息先解码消息体再进入广播流程
+	hub.SetPipeline(pipeline.New(pipeline.Decode(func(raw interface{}) (interface{}, error) {
+		if message, ok := raw.(kafka.Message); ok {
+			return pipeline.JSON(message.Value)
+		}
+		return raw, nil
+	})).ForSources("kafka"))
+
+	// scripts 目录下的 lua 脚本注册为主题，修改后自动重新加载
+	if _, err := os.Stat("scripts"); err == nil {
+		loader := script.NewLoader(hub, "scripts", script.Options{})
+		if err := loader.Load(); err != nil {
+			logrus.Errorf("Load Scripts Error: %s", err.Error())
+		}
+		go loader.Watch(context.Background())
+	}
 
 	config := connector.NewKafkaConfig("group1", "test-topic", "localhost:9092")
 	reader := connector.NewKafkaReader(config)
@@ -52,17 +108,42 @@ func main() {
 var upgrader = websocket.Upgrader{}
 
 func Connect(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	client, err := hub.Upgrade(c.Writer, c.Request, &upgrader)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err.Error())
+		logrus.Warnf("%s Connect Failed: %s", c.ClientIP(), err.Error())
 		return
 	}
 
-	client := pusher.NewClient(hub, conn)
-	client.User().SetUser("who am i")
+	if client.User().User() == nil {
+		client.User().SetUser("who am i")
+	}
 	client.Run()
 }
 
+// Notify 定向推送给用户的所有连接
+func Notify(c *gin.Context) {
+	var body interface{}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	count := hub.SendToUser(c.Param("user"), pusher.NewMessage("notification", body, true))
+	c.JSON(http.StatusOK, gin.H{"connections": count})
+}
+
+type AckAlarmParams struct {
+	AlarmID int `json:"alarmId"`
+}
+
+// AckAlarm 客户端调用示例: {"id":"1","method":"ackAlarm","params":{"alarmId":10}}
+func AckAlarm(ctx context.Context, client pusher.Client, params AckAlarmParams) (interface{}, error) {
+	if params.AlarmID <= 0 {
+		return nil, pusher.NewError(pusher.CodeInvalidParams, "alarmId is required")
+	}
+	logrus.Infof("%s ack alarm: %d", client.RemoteAddr(), params.AlarmID)
+	return map[string]int{"alarmId": params.AlarmID}, nil
+}
+
 func test() {
 	time.Sleep(time.Second * 5)
 	logrus.Infof("test start")
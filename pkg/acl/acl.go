@@ -0,0 +1,168 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: acl
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 18:45
+ */
+
+package acl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Rule 按用户声明匹配主题，claims 中每个声明至少命中一个取值才算匹配，
+// 声明值为数组时（如 roles）任一元素命中即可，"*" 匹配任意取值
+//
+//	{"effect":"allow","claims":{"roles":["dispatcher"]},"topics":["car","alarm.*"]}
+type Rule struct {
+	Effect string              `json:"effect"`
+	Claims map[string][]string `json:"claims"`
+	// Anonymous 为 true 时仅匹配匿名用户（无声明）
+	Anonymous bool     `json:"anonymous"`
+	Topics    []string `json:"topics"`
+}
+
+// ACL 声明式订阅权限，规则按顺序匹配，首个命中的规则生效，均未命中时使用 Default
+//
+//	{
+//	  "default": "deny",
+//	  "rules": [
+//	    {"effect":"allow","claims":{"roles":["admin"]},"topics":["*"]},
+//	    {"effect":"allow","claims":{"roles":["dispatcher"]},"topics":["car","alarm.*"]},
+//	    {"effect":"allow","anonymous":true,"topics":["public.*"]}
+//	  ]
+//	}
+type ACL struct {
+	Default string `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+// LoadFile 从 json 文件加载 ACL
+func LoadFile(filename string) (*ACL, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var acl ACL
+	if err := json.Unmarshal(raw, &acl); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	if err := acl.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return &acl, nil
+}
+
+func (acl *ACL) validate() error {
+	if acl.Default == "" {
+		acl.Default = EffectDeny
+	}
+	if acl.Default != EffectAllow && acl.Default != EffectDeny {
+		return fmt.Errorf("illegal default effect: %s", acl.Default)
+	}
+	for i := range acl.Rules {
+		rule := &acl.Rules[i]
+		if rule.Effect == "" {
+			rule.Effect = EffectAllow
+		}
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %d: illegal effect: %s", i, rule.Effect)
+		}
+		for j, pattern := range rule.Topics {
+			rule.Topics[j] = strings.ToLower(pattern)
+			if _, err := path.Match(rule.Topics[j], ""); err != nil {
+				return fmt.Errorf("rule %d: illegal topic pattern %q", i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Policy 转换为 Hub 的全局订阅策略
+//
+//	hub.SetSubscribePolicy(acl.Policy())
+func (acl *ACL) Policy() pusher.SubscribePolicy {
+	return func(user pusher.User, topic string, _ json.RawMessage) error {
+		return acl.Check(user.Claims(), topic)
+	}
+}
+
+// Check 校验声明是否有权订阅主题
+func (acl *ACL) Check(claims map[string]interface{}, topic string) error {
+	topic = strings.ToLower(topic)
+	for _, rule := range acl.Rules {
+		if !rule.matchTopic(topic) || !rule.matchClaims(claims) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			return pusher.NewError(pusher.CodeForbidden, "topic %s denied by acl", topic)
+		}
+		return nil
+	}
+	if acl.Default == EffectAllow {
+		return nil
+	}
+	return pusher.NewError(pusher.CodeForbidden, "topic %s not allowed", topic)
+}
+
+func (rule *Rule) matchTopic(topic string) bool {
+	for _, pattern := range rule.Topics {
+		if ok, _ := path.Match(pattern, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *Rule) matchClaims(claims map[string]interface{}) bool {
+	if rule.Anonymous {
+		return len(claims) == 0
+	}
+	for name, values := range rule.Claims {
+		if !matchClaim(claims[name], values) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchClaim(claim interface{}, values []string) bool {
+	switch v := claim.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if matchClaim(item, values) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, item := range v {
+			if matchClaim(item, values) {
+				return true
+			}
+		}
+		return false
+	}
+	actual := fmt.Sprint(claim)
+	for _, value := range values {
+		if value == "*" || value == actual {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: acl_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 10:40
+ */
+
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testACL = `{
+  "default": "deny",
+  "rules": [
+    {"effect":"deny","claims":{"roles":["dispatcher"]},"topics":["alarm.secret"]},
+    {"effect":"allow","claims":{"roles":["admin"]},"topics":["*"]},
+    {"effect":"allow","claims":{"roles":["dispatcher"],"region":["*"]},"topics":["Car","alarm.*"]},
+    {"effect":"allow","anonymous":true,"topics":["public.*"]}
+  ]
+}`
+
+func loadTestACL(t *testing.T, content string) (*ACL, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "acl.json")
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadFile(filename)
+}
+
+func TestCheck(t *testing.T) {
+	acl, err := loadTestACL(t, testACL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := map[string]interface{}{"roles": []interface{}{"viewer", "dispatcher"}, "region": "east"}
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		topic  string
+		allow  bool
+	}{
+		{name: "admin wildcard", claims: map[string]interface{}{"roles": []string{"admin"}}, topic: "anything", allow: true},
+		{name: "topic case insensitive", claims: dispatcher, topic: "CAR", allow: true},
+		{name: "glob pattern", claims: dispatcher, topic: "alarm.fire", allow: true},
+		{name: "glob does not cross dots", claims: dispatcher, topic: "alarmfire", allow: false},
+		{name: "earlier deny wins", claims: dispatcher, topic: "alarm.secret", allow: false},
+		{name: "missing claim", claims: map[string]interface{}{"roles": "dispatcher"}, topic: "car", allow: false},
+		{name: "scalar claim", claims: map[string]interface{}{"roles": "dispatcher", "region": 7}, topic: "car", allow: true},
+		{name: "anonymous", claims: nil, topic: "public.news", allow: true},
+		{name: "anonymous rule skips users", claims: dispatcher, topic: "public.news", allow: false},
+		{name: "default deny", claims: nil, topic: "car", allow: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := acl.Check(tt.claims, tt.topic)
+			if (err == nil) != tt.allow {
+				t.Fatalf("Check(%v, %s) = %v, want allow %v", tt.claims, tt.topic, err, tt.allow)
+			}
+		})
+	}
+}
+
+func TestLoadFileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{name: "default effect", content: `{"rules":[{"topics":["car"]}]}`, valid: true},
+		{name: "illegal default", content: `{"default":"maybe"}`, valid: false},
+		{name: "illegal effect", content: `{"rules":[{"effect":"grant","topics":["car"]}]}`, valid: false},
+		{name: "illegal pattern", content: `{"rules":[{"topics":["car["]}]}`, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acl, err := loadTestACL(t, tt.content)
+			if (err == nil) != tt.valid {
+				t.Fatalf("LoadFile error = %v, want valid %v", err, tt.valid)
+			}
+			if err == nil && (acl.Default != EffectDeny || acl.Rules[0].Effect != EffectAllow) {
+				t.Fatalf("defaults not applied: %+v", acl)
+			}
+		})
+	}
+}
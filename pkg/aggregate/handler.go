@@ -0,0 +1,305 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: handler
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 16:30
+ */
+
+package aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+// Source 聚合结果数据的来源
+const Source = "aggregate"
+
+// 分组排序依据
+const (
+	OrderCount = "count"
+	OrderSum   = "sum"
+	OrderAvg   = "avg"
+	OrderMin   = "min"
+	OrderMax   = "max"
+)
+
+type Config struct {
+	// Topic 主题名
+	Topic string
+	// Window 窗口长度
+	Window time.Duration
+	// Slide 滑动步长，为 0 或等于 Window 时为滚动窗口，需能整除 Window，否则 NewHandler 返回错误
+	Slide time.Duration
+	// KeyField 分组字段，Key 不为空时优先使用 Key，都为空时不分组
+	KeyField string
+	Key      func(data pusher.Data) (string, bool)
+	// ValueField 统计字段，Value 不为空时优先使用 Value，都为空时仅计数
+	ValueField string
+	Value      func(data pusher.Data) (float64, bool)
+	// OrderBy 分组排序依据，默认 count，Top 大于 0 时只保留前 Top 个分组
+	OrderBy string
+	Top     int
+	// Tick 大于 0 时按间隔推送当前未结束的窗口，窗口结束时总会推送
+	Tick time.Duration
+}
+
+// Handler 窗口聚合 Handler，按分组统计窗口内的数据，窗口结束时（滑动窗口为每个步长）推送聚合结果，
+// 订阅时以当前未结束窗口的聚合结果作为首次数据。ClusterSharded 模式下仅属主节点有统计数据，
+// 其他节点订阅者的首次数据为空窗口，之后的聚合结果由属主节点推送
+//
+//	handler, err := aggregate.NewHandler(hub, aggregate.Config{
+//		Topic:      "SpeedPerMinute",
+//		Window:     time.Minute,
+//		KeyField:   "vin",
+//		ValueField: "speed",
+//		OrderBy:    aggregate.OrderAvg,
+//		Top:        10,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	hub.TopicRegister(handler)
+//	defer hub.TopicUnRegister(handler)
+type Handler struct {
+	hub        *pusher.Hub
+	config     Config
+	state      *state
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+}
+
+type state struct {
+	mutex   sync.Mutex
+	windows *windows
+	cancel  context.CancelFunc
+}
+
+// NewHandler 创建聚合 Handler，注册到 Hub 后开始计时，注销主题时停止
+func NewHandler(hub *pusher.Hub, config Config) (*Handler, error) {
+	if config.Slide == 0 {
+		config.Slide = config.Window
+	}
+	if config.Window <= 0 || config.Slide <= 0 {
+		return nil, fmt.Errorf("window %s and slide %s must be positive", config.Window, config.Slide)
+	}
+	if config.Window%config.Slide != 0 {
+		return nil, fmt.Errorf("slide %s does not divide window %s", config.Slide, config.Window)
+	}
+	if config.OrderBy == "" {
+		config.OrderBy = OrderCount
+	}
+	h := &Handler{
+		hub:    hub,
+		config: config,
+		state: &state{
+			windows: newWindows(config.Window, config.Slide, time.Now()),
+		},
+	}
+	return h, nil
+}
+
+// OnRegister 注册主题时开始计时
+func (h *Handler) OnRegister() {
+	h.state.mutex.Lock()
+	defer h.state.mutex.Unlock()
+
+	if h.state.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.state.cancel = cancel
+	go h.run(ctx)
+}
+
+// OnUnRegister 注销主题时停止计时及推送
+func (h *Handler) OnUnRegister() {
+	h.state.mutex.Lock()
+	defer h.state.mutex.Unlock()
+
+	if h.state.cancel != nil {
+		h.state.cancel()
+		h.state.cancel = nil
+	}
+}
+
+// Stop 停止计时及推送，注销主题时会自动调用
+func (h *Handler) Stop() {
+	h.OnUnRegister()
+}
+
+func (h *Handler) run(ctx context.Context) {
+	var tick <-chan time.Time
+	if h.config.Tick > 0 {
+		ticker := time.NewTicker(h.config.Tick)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	now := time.Now()
+	timer := time.NewTimer(now.Truncate(h.config.Slide).Add(h.config.Slide).Sub(now))
+	defer timer.Stop()
+	for {
+		select {
+		case now := <-timer.C:
+			h.state.mutex.Lock()
+			result := aggregate(h.state.windows.rotate(now), now, true, h.config.OrderBy, h.config.Top)
+			h.state.mutex.Unlock()
+			h.emit(result)
+			timer.Reset(now.Truncate(h.config.Slide).Add(h.config.Slide).Sub(time.Now()))
+		case <-tick:
+			h.emit(h.partial())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// emit 推送聚合结果，分片模式下仅主题属主节点推送
+func (h *Handler) emit(result *Result) {
+	if !h.hub.Owns(h.config.Topic) {
+		return
+	}
+	h.hub.EmitTopic(h.config.Topic, pusher.NewData(Source, result))
+}
+
+// partial 当前未结束窗口的聚合结果
+func (h *Handler) partial() *Result {
+	h.state.mutex.Lock()
+	defer h.state.mutex.Unlock()
+
+	return aggregate(h.state.windows.buckets, time.Now(), false, h.config.OrderBy, h.config.Top)
+}
+
+func (h *Handler) Name() string {
+	return h.config.Topic
+}
+
+func (h *Handler) Handle(data pusher.Data) {
+	if data.Metadata().Source() == Source {
+		return
+	}
+	// 分组字段和统计字段共用一次解码结果
+	var object map[string]interface{}
+	if (h.config.Key == nil && h.config.KeyField != "") || (h.config.Value == nil && h.config.ValueField != "") {
+		object = decode(data.Raw())
+	}
+	key, ok := h.key(data, object)
+	if !ok {
+		return
+	}
+	value, hasValue := h.value(data, object)
+
+	h.state.mutex.Lock()
+	defer h.state.mutex.Unlock()
+
+	h.state.windows.add(key, value, hasValue)
+}
+
+func (h *Handler) key(data pusher.Data, object map[string]interface{}) (string, bool) {
+	if h.config.Key != nil {
+		return h.config.Key(data)
+	}
+	if h.config.KeyField == "" {
+		return "", true
+	}
+	value, exists := object[h.config.KeyField]
+	if !exists || value == nil {
+		return "", false
+	}
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return fmt.Sprint(value), true
+}
+
+func (h *Handler) value(data pusher.Data, object map[string]interface{}) (float64, bool) {
+	if h.config.Value != nil {
+		return h.config.Value(data)
+	}
+	if h.config.ValueField == "" {
+		return 0, false
+	}
+	value, exists := object[h.config.ValueField]
+	if !exists {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// decode 将数据转换为 map，非 map 数据先经 json 编解码，失败时返回 nil
+func decode(raw interface{}) map[string]interface{} {
+	if object, ok := raw.(map[string]interface{}); ok {
+		return object
+	}
+	var body []byte
+	switch v := raw.(type) {
+	case json.RawMessage:
+		body = v
+	case []byte:
+		body = v
+	default:
+		var err error
+		if body, err = json.Marshal(raw); err != nil {
+			return nil
+		}
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil
+	}
+	return object
+}
+
+// TopicView 仅推送聚合结果，原始数据不推送给订阅者
+func (h *Handler) TopicView(data pusher.Data, user pusher.User) {
+	if data == nil || data.Metadata().Source() != Source {
+		return
+	}
+	user.Write(pusher.NewMessage(h.config.Topic, data.Raw(), false))
+}
+
+// LoadSnapshot 以当前未结束窗口的聚合结果作为首次数据
+func (h *Handler) LoadSnapshot(context.Context, pusher.User, json.RawMessage) (interface{}, error) {
+	return h.partial(), nil
+}
+
+func (h *Handler) SetContext(ctx context.Context, cancelFunc context.CancelFunc) {
+	h.ctx = ctx
+	h.cancelFunc = cancelFunc
+}
+
+func (h *Handler) Clone() pusher.Handler {
+	return &Handler{
+		hub:    h.hub,
+		config: h.config,
+		state:  h.state,
+	}
+}
+
+var (
+	_ pusher.Handler     = (*Handler)(nil)
+	_ pusher.Snapshotter = (*Handler)(nil)
+)
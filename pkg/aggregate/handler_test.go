@@ -0,0 +1,109 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: handler_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 14:20
+ */
+
+package aggregate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+func TestHandlerLifecycle(t *testing.T) {
+	hub := pusher.NewHub()
+	handler, err := NewHandler(hub, Config{Topic: "AggregateLifecycle", Window: time.Minute, Tick: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handler.state.cancel != nil {
+		t.Fatal("timer started before register")
+	}
+	hub.TopicRegister(handler)
+	if handler.state.cancel == nil {
+		t.Fatal("timer not started on register")
+	}
+	hub.TopicUnRegister(handler)
+	if handler.state.cancel != nil {
+		t.Fatal("timer not stopped on unregister")
+	}
+	// 重新注册后再次计时
+	hub.TopicRegister(handler)
+	defer hub.TopicUnRegister(handler)
+	if handler.state.cancel == nil {
+		t.Fatal("timer not restarted on register")
+	}
+}
+
+func TestHandlerFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   interface{}
+		key   string
+		value float64
+		ok    bool
+	}{
+		{name: "map", raw: map[string]interface{}{"vin": "v1", "speed": 60.0}, key: "v1", value: 60, ok: true},
+		{name: "raw json", raw: json.RawMessage(`{"vin":7,"speed":"42.5"}`), key: "7", value: 42.5, ok: true},
+		{name: "struct", raw: struct {
+			Vin   string  `json:"vin"`
+			Speed float32 `json:"speed"`
+		}{"v2", 30}, key: "v2", value: 30, ok: true},
+		{name: "missing key", raw: map[string]interface{}{"speed": 1.0}},
+		{name: "not an object", raw: json.RawMessage(`[1,2]`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(nil, Config{Topic: "AggregateFields", Window: time.Minute, KeyField: "vin", ValueField: "speed"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler.Handle(pusher.NewData("test", tt.raw))
+			result := handler.partial()
+			if !tt.ok {
+				if len(result.Groups) != 0 {
+					t.Fatalf("groups = %+v, want none", result.Groups)
+				}
+				return
+			}
+			if len(result.Groups) != 1 || result.Groups[0].Key != tt.key || result.Groups[0].Sum != tt.value {
+				t.Fatalf("groups = %+v, want key %s value %v", result.Groups, tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestHandlerConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		slide  time.Duration
+		ok     bool
+	}{
+		{name: "tumbling", window: time.Minute, ok: true},
+		{name: "sliding", window: time.Minute, slide: 15 * time.Second, ok: true},
+		{name: "slide equals window", window: time.Minute, slide: time.Minute, ok: true},
+		{name: "slide does not divide window", window: time.Minute, slide: 25 * time.Second},
+		{name: "slide exceeds window", window: time.Minute, slide: 2 * time.Minute},
+		{name: "negative slide", window: time.Minute, slide: -time.Second},
+		{name: "no window", slide: time.Second},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHandler(nil, Config{Topic: "AggregateConfig", Window: tt.window, Slide: tt.slide})
+			if tt.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("invalid window accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: window
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 16:00
+ */
+
+package aggregate
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats 单个分组的统计值，Count 为数据条数，Sum、Avg、Min、Max 仅统计取到数值的数据
+type Stats struct {
+	Key   string  `json:"key"`
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Avg   float64 `json:"avg"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	// values 取到数值的数据条数
+	values int64
+}
+
+func (s *Stats) add(value float64, hasValue bool) {
+	s.Count++
+	if !hasValue {
+		return
+	}
+	if s.values == 0 || value < s.Min {
+		s.Min = value
+	}
+	if s.values == 0 || value > s.Max {
+		s.Max = value
+	}
+	s.values++
+	s.Sum += value
+}
+
+func (s *Stats) merge(other *Stats) {
+	s.Count += other.Count
+	if other.values == 0 {
+		return
+	}
+	if s.values == 0 {
+		s.Min, s.Max = other.Min, other.Max
+	} else {
+		s.Min = math.Min(s.Min, other.Min)
+		s.Max = math.Max(s.Max, other.Max)
+	}
+	s.values += other.values
+	s.Sum += other.Sum
+}
+
+// Result 窗口聚合结果，Closed 为 false 时表示当前未结束的窗口
+type Result struct {
+	Start  int64    `json:"start"`
+	End    int64    `json:"end"`
+	Closed bool     `json:"closed"`
+	Groups []*Stats `json:"groups"`
+}
+
+// bucket 滑动步长内的统计
+type bucket struct {
+	start  time.Time
+	groups map[string]*Stats
+}
+
+// windows 按滑动步长分桶，窗口由最近 size 个桶组成，滚动窗口时 size 为 1
+type windows struct {
+	slide   time.Duration
+	size    int
+	buckets []*bucket
+}
+
+func newWindows(window, slide time.Duration, now time.Time) *windows {
+	size := int(window / slide)
+	if size < 1 {
+		size = 1
+	}
+	w := &windows{slide: slide, size: size}
+	w.buckets = append(w.buckets, &bucket{start: now.Truncate(slide), groups: make(map[string]*Stats)})
+	return w
+}
+
+func (w *windows) current() *bucket {
+	return w.buckets[len(w.buckets)-1]
+}
+
+func (w *windows) add(key string, value float64, hasValue bool) {
+	b := w.current()
+	stats, exists := b.groups[key]
+	if !exists {
+		stats = &Stats{Key: key}
+		b.groups[key] = stats
+	}
+	stats.add(value, hasValue)
+}
+
+// rotate 关闭当前桶并开启新桶，返回刚结束的窗口
+func (w *windows) rotate(now time.Time) []*bucket {
+	closed := append([]*bucket(nil), w.buckets...)
+	w.buckets = append(w.buckets, &bucket{start: now.Truncate(w.slide), groups: make(map[string]*Stats)})
+	if len(w.buckets) > w.size {
+		w.buckets = w.buckets[len(w.buckets)-w.size:]
+	}
+	return closed
+}
+
+// aggregate 合并桶内统计，按 order 降序排列后取前 top 个分组，top 为 0 表示全部
+func aggregate(buckets []*bucket, end time.Time, closed bool, order string, top int) *Result {
+	merged := make(map[string]*Stats)
+	for _, b := range buckets {
+		for key, stats := range b.groups {
+			if m, exists := merged[key]; exists {
+				m.merge(stats)
+				continue
+			}
+			copied := *stats
+			merged[key] = &copied
+		}
+	}
+	groups := make([]*Stats, 0, len(merged))
+	for _, stats := range merged {
+		if stats.values > 0 {
+			stats.Avg = stats.Sum / float64(stats.values)
+		}
+		groups = append(groups, stats)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := metric(groups[i], order), metric(groups[j], order)
+		if a == b {
+			return groups[i].Key < groups[j].Key
+		}
+		return a > b
+	})
+	if top > 0 && len(groups) > top {
+		groups = groups[:top]
+	}
+	result := &Result{End: end.UnixMilli(), Closed: closed, Groups: groups}
+	if len(buckets) > 0 {
+		result.Start = buckets[0].start.UnixMilli()
+	}
+	return result
+}
+
+func metric(stats *Stats, order string) float64 {
+	switch order {
+	case OrderSum:
+		return stats.Sum
+	case OrderAvg:
+		return stats.Avg
+	case OrderMin:
+		return stats.Min
+	case OrderMax:
+		return stats.Max
+	}
+	return float64(stats.Count)
+}
@@ -0,0 +1,98 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: window_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 14:00
+ */
+
+package aggregate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowsRotate(t *testing.T) {
+	start := time.Date(2026, 10, 25, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		window time.Duration
+		slide  time.Duration
+		// counts 每个步长内 key "a" 的数据条数，每个步长结束时轮转一次
+		counts []int
+		// want 每次轮转后关闭窗口的计数及起始步长
+		want      []int64
+		wantStart []int
+	}{
+		{name: "tumbling", window: time.Minute, slide: time.Minute, counts: []int{1, 2, 3}, want: []int64{1, 2, 3}, wantStart: []int{0, 1, 2}},
+		{name: "sliding", window: 3 * time.Minute, slide: time.Minute, counts: []int{1, 2, 3, 4, 5}, want: []int64{1, 3, 6, 9, 12}, wantStart: []int{0, 0, 0, 1, 2}},
+		{name: "empty slide", window: 2 * time.Minute, slide: time.Minute, counts: []int{1, 0, 0}, want: []int64{1, 1, 0}, wantStart: []int{0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWindows(tt.window, tt.slide, start)
+			for i, count := range tt.counts {
+				for j := 0; j < count; j++ {
+					w.add("a", float64(j), true)
+				}
+				end := start.Add(time.Duration(i+1) * tt.slide)
+				result := aggregate(w.rotate(end), end, true, OrderCount, 0)
+				var got int64
+				if len(result.Groups) > 0 {
+					got = result.Groups[0].Count
+				}
+				if got != tt.want[i] {
+					t.Fatalf("rotation %d count = %d, want %d", i, got, tt.want[i])
+				}
+				if wantStart := start.Add(time.Duration(tt.wantStart[i]) * tt.slide).UnixMilli(); result.Start != wantStart {
+					t.Fatalf("rotation %d start = %d, want %d", i, result.Start, wantStart)
+				}
+				if size := len(w.buckets); size > w.size {
+					t.Fatalf("rotation %d keeps %d buckets, want at most %d", i, size, w.size)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateOrder(t *testing.T) {
+	now := time.Now()
+	w := newWindows(time.Minute, time.Minute, now)
+	for _, item := range []struct {
+		key   string
+		value float64
+	}{{"a", 1}, {"a", 3}, {"b", 10}, {"c", 4}, {"c", 5}, {"c", 6}} {
+		w.add(item.key, item.value, true)
+	}
+	w.add("a", 0, false)
+	tests := []struct {
+		order string
+		top   int
+		want  []string
+	}{
+		{order: OrderCount, want: []string{"a", "c", "b"}},
+		{order: OrderSum, top: 2, want: []string{"c", "b"}},
+		{order: OrderAvg, want: []string{"b", "c", "a"}},
+		{order: OrderMin, top: 1, want: []string{"b"}},
+		{order: OrderMax, want: []string{"b", "c", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			result := aggregate(w.buckets, now, false, tt.order, tt.top)
+			if len(result.Groups) != len(tt.want) {
+				t.Fatalf("groups = %d, want %d", len(result.Groups), len(tt.want))
+			}
+			for i, key := range tt.want {
+				if result.Groups[i].Key != key {
+					t.Fatalf("group %d = %s, want %s", i, result.Groups[i].Key, key)
+				}
+			}
+		})
+	}
+	// 未取到数值的数据只计数，不参与均值
+	result := aggregate(w.buckets, now, false, OrderCount, 0)
+	if a := result.Groups[0]; a.Count != 3 || a.Avg != 2 || a.Min != 1 || a.Max != 3 {
+		t.Fatalf("stats of a = %+v", a)
+	}
+}
@@ -0,0 +1,157 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: jwt
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 16:40
+ */
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+// 默认允许的签名算法
+var defaultAlgorithms = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"ES256", "ES384", "ES512",
+}
+
+type JWTConfig struct {
+	// Keys 验签公钥/密钥
+	Keys *KeySet
+	// Algorithms 允许的签名算法，默认 HS/RS/ES 全部
+	Algorithms []string
+	// QueryParam 从 url 参数中读取令牌，默认 token
+	QueryParam string
+	// Subprotocol 从 Sec-WebSocket-Protocol 读取令牌，客户端以 "access_token, <jwt>" 形式传递，默认 access_token。
+	// 需同时将其加入 websocket.Upgrader.Subprotocols，否则浏览器会拒绝握手
+	Subprotocol string
+	// Issuer、Audience 不为空时校验
+	Issuer   string
+	Audience string
+	// Leeway 时间校验容差
+	Leeway time.Duration
+	// UserFunc 从声明中提取用户信息，默认取 sub
+	UserFunc func(claims jwt.MapClaims) interface{}
+	// Authorize 令牌有效后的额外校验，返回错误时以 403 拒绝
+	Authorize func(claims jwt.MapClaims) error
+}
+
+// JWTAuthenticator 基于 JWT 的连接鉴权，令牌按 Authorization 头、url 参数、Sec-WebSocket-Protocol 的顺序读取
+type JWTAuthenticator struct {
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+func NewJWTAuthenticator(config JWTConfig) *JWTAuthenticator {
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = defaultAlgorithms
+	}
+	if config.QueryParam == "" {
+		config.QueryParam = "token"
+	}
+	if config.Subprotocol == "" {
+		config.Subprotocol = "access_token"
+	}
+	if config.UserFunc == nil {
+		config.UserFunc = subject
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(config.Algorithms),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	return &JWTAuthenticator{
+		config: config,
+		parser: jwt.NewParser(options...),
+	}
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*pusher.Credentials, error) {
+	token := a.extract(r)
+	if token == "" {
+		return nil, pusher.NewAuthError(http.StatusUnauthorized, "token is missing")
+	}
+	return a.verify(token)
+}
+
+func (a *JWTAuthenticator) Refresh(_ context.Context, token string) (*pusher.Credentials, error) {
+	return a.verify(token)
+}
+
+func (a *JWTAuthenticator) extract(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+			return strings.TrimSpace(header[7:])
+		}
+	}
+	if token := r.URL.Query().Get(a.config.QueryParam); token != "" {
+		return token
+	}
+	protocols := websocketProtocols(r)
+	for i, protocol := range protocols {
+		if protocol == a.config.Subprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func (a *JWTAuthenticator) verify(token string) (*pusher.Credentials, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.config.Keys.keyFunc); err != nil {
+		return nil, pusher.NewAuthError(http.StatusUnauthorized, "invalid token: %s", err.Error())
+	}
+	if a.config.Authorize != nil {
+		if err := a.config.Authorize(claims); err != nil {
+			return nil, pusher.NewAuthError(http.StatusForbidden, "%s", err.Error())
+		}
+	}
+
+	credentials := &pusher.Credentials{
+		User:   a.config.UserFunc(claims),
+		Claims: claims,
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		credentials.ExpiresAt = exp.Time
+	}
+	return credentials, nil
+}
+
+func subject(claims jwt.MapClaims) interface{} {
+	sub, _ := claims.GetSubject()
+	return sub
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+var (
+	_ pusher.Authenticator = (*JWTAuthenticator)(nil)
+	_ pusher.Refresher     = (*JWTAuthenticator)(nil)
+)
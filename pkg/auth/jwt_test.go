@@ -0,0 +1,204 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: jwt_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/26 10:00
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestExtractOrder(t *testing.T) {
+	a := NewJWTAuthenticator(JWTConfig{})
+	tests := []struct {
+		name        string
+		header      string
+		query       string
+		subprotocol string
+		want        string
+	}{
+		{name: "header", header: "Bearer h", want: "h"},
+		{name: "case insensitive scheme", header: "bearer h", want: "h"},
+		{name: "query", query: "q", want: "q"},
+		{name: "subprotocol", subprotocol: "v2, access_token, s", want: "s"},
+		{name: "header before query", header: "Bearer h", query: "q", subprotocol: "access_token, s", want: "h"},
+		{name: "query before subprotocol", query: "q", subprotocol: "access_token, s", want: "q"},
+		{name: "non bearer header", header: "Basic b", query: "q", want: "q"},
+		{name: "subprotocol without token", subprotocol: "access_token"},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/ws"
+			if tt.query != "" {
+				target += "?token=" + tt.query
+			}
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.subprotocol != "" {
+				r.Header.Set("Sec-WebSocket-Protocol", tt.subprotocol)
+			}
+			if got := a.extract(r); got != tt.want {
+				t.Fatalf("token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAlgorithm(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeySet()
+	keys.AddHMAC("", secret)
+	if err := keys.AddPublicKey("rsa", &rsaKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPublicKey("ec", &ecKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": "alice"}
+	tests := []struct {
+		name       string
+		algorithms []string
+		token      string
+		ok         bool
+	}{
+		{name: "hs256", token: sign(t, jwt.SigningMethodHS256, secret, "", claims), ok: true},
+		{name: "rs256", token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", claims), ok: true},
+		{name: "es256", token: sign(t, jwt.SigningMethodES256, ecKey, "ec", claims), ok: true},
+		{name: "none", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims)},
+		{name: "not allowed", algorithms: []string{"RS256"}, token: sign(t, jwt.SigningMethodHS256, secret, "", claims)},
+		// 以公钥内容作为 HMAC 密钥伪造的令牌
+		{name: "key confusion", token: sign(t, jwt.SigningMethodHS256, der, "rsa", claims)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewJWTAuthenticator(JWTConfig{Keys: keys, Algorithms: tt.algorithms})
+			credentials, err := a.verify(tt.token)
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("token accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if credentials.User != "alice" {
+				t.Fatalf("user = %v, want alice", credentials.User)
+			}
+		})
+	}
+}
+
+func TestVerifyKid(t *testing.T) {
+	keys := NewKeySet()
+	keys.AddHMAC("", []byte("default"))
+	keys.AddHMAC("a", []byte("a"))
+	keys.AddHMAC("b", []byte("b"))
+	claims := jwt.MapClaims{"sub": "alice"}
+	tests := []struct {
+		name  string
+		keys  *KeySet
+		token string
+		ok    bool
+	}{
+		{name: "kid", keys: keys, token: sign(t, jwt.SigningMethodHS256, []byte("a"), "a", claims), ok: true},
+		{name: "no kid uses default", keys: keys, token: sign(t, jwt.SigningMethodHS256, []byte("default"), "", claims), ok: true},
+		{name: "wrong key for kid", keys: keys, token: sign(t, jwt.SigningMethodHS256, []byte("a"), "b", claims)},
+		{name: "unknown kid", keys: keys, token: sign(t, jwt.SigningMethodHS256, []byte("a"), "c", claims)},
+		{name: "no default key", keys: NewKeySet(), token: sign(t, jwt.SigningMethodHS256, []byte("default"), "", claims)},
+		{name: "no keys", token: sign(t, jwt.SigningMethodHS256, []byte("default"), "", claims)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJWTAuthenticator(JWTConfig{Keys: tt.keys}).verify(tt.token)
+			if tt.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
+
+func TestVerifyTime(t *testing.T) {
+	secret := []byte("secret")
+	keys := NewKeySet()
+	keys.AddHMAC("", secret)
+	now := time.Now()
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		leeway time.Duration
+		ok     bool
+	}{
+		{name: "valid", claims: jwt.MapClaims{"exp": now.Add(time.Minute).Unix(), "nbf": now.Add(-time.Minute).Unix()}, ok: true},
+		{name: "expired", claims: jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()}},
+		{name: "expired within leeway", claims: jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()}, leeway: 2 * time.Minute, ok: true},
+		{name: "not yet valid", claims: jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()}},
+		{name: "not yet valid within leeway", claims: jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()}, leeway: 2 * time.Minute, ok: true},
+		{name: "no exp", claims: jwt.MapClaims{"sub": "alice"}, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewJWTAuthenticator(JWTConfig{Keys: keys, Leeway: tt.leeway})
+			credentials, err := a.verify(sign(t, jwt.SigningMethodHS256, secret, "", tt.claims))
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("token accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 凭证过期时间取自 exp
+			exp, _ := tt.claims["exp"].(int64)
+			if (exp == 0) != credentials.ExpiresAt.IsZero() || (exp != 0 && credentials.ExpiresAt.Unix() != exp) {
+				t.Fatalf("expires at = %v, want %d", credentials.ExpiresAt, exp)
+			}
+		})
+	}
+}
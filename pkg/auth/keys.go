@@ -0,0 +1,198 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: keys
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 17:05
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet 验签密钥集合，按令牌头部的 kid 查找，令牌未携带 kid 时使用 kid 为空的密钥
+type KeySet struct {
+	mutex sync.RWMutex
+	keys  map[string]interface{}
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys: make(map[string]interface{}),
+	}
+}
+
+// AddHMAC 添加 HS 系列算法密钥
+func (ks *KeySet) AddHMAC(kid string, secret []byte) {
+	ks.add(kid, secret)
+}
+
+// AddPublicKey 添加 RS/ES 系列算法公钥
+func (ks *KeySet) AddPublicKey(kid string, key crypto.PublicKey) error {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		ks.add(kid, key)
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type: %T", key)
+}
+
+// AddPEMFile 从 PEM 文件添加公钥或证书
+func (ks *KeySet) AddPEMFile(kid string, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return fmt.Errorf("%s: no pem block found", path)
+	}
+
+	var key crypto.PublicKey
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return ks.AddPublicKey(kid, key)
+}
+
+func (ks *KeySet) add(kid string, key interface{}) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	ks.keys[kid] = key
+}
+
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks == nil {
+		return nil, fmt.Errorf("no verification keys configured")
+	}
+	kid, _ := token.Header["kid"].(string)
+
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+
+	key, exists := ks.keys[kid]
+	if !exists {
+		return nil, fmt.Errorf("key not found: %q", kid)
+	}
+	return key, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+// LoadJWKSFile 从本地 JWKS 文件加载密钥，支持 RSA、EC 与 oct 类型
+func LoadJWKSFile(path string) (*KeySet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &jwks); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	ks := NewKeySet()
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if err := ks.addJWK(key); err != nil {
+			return nil, fmt.Errorf("%s: kid %q: %s", path, key.Kid, err.Error())
+		}
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) addJWK(key jwk) error {
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+		if err != nil {
+			return err
+		}
+		e, err := decodeBigInt(key.E)
+		if err != nil {
+			return err
+		}
+		return ks.AddPublicKey(key.Kid, &rsa.PublicKey{N: n, E: int(e.Int64())})
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return fmt.Errorf("unsupported curve: %s", key.Crv)
+		}
+		x, err := decodeBigInt(key.X)
+		if err != nil {
+			return err
+		}
+		y, err := decodeBigInt(key.Y)
+		if err != nil {
+			return err
+		}
+		return ks.AddPublicKey(key.Kid, &ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(key.K)
+		if err != nil {
+			return err
+		}
+		ks.AddHMAC(key.Kid, secret)
+		return nil
+	}
+	return fmt.Errorf("unsupported key type: %s", key.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,108 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: keys_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/26 10:30
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("secret")
+	path := writeJWKS(t,
+		map[string]string{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		map[string]string{"kty": "EC", "kid": "ec", "crv": "P-384", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+		map[string]string{"kty": "oct", "kid": "oct", "k": base64.RawURLEncoding.EncodeToString(secret)},
+		// 非签名用途的密钥被忽略
+		map[string]string{"kty": "oct", "kid": "enc", "use": "enc", "k": base64.RawURLEncoding.EncodeToString(secret)},
+	)
+	keys, err := LoadJWKSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewJWTAuthenticator(JWTConfig{Keys: keys})
+	claims := jwt.MapClaims{"sub": "alice"}
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{name: "rsa", token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", claims), ok: true},
+		{name: "ec", token: sign(t, jwt.SigningMethodES384, ecKey, "ec", claims), ok: true},
+		{name: "oct", token: sign(t, jwt.SigningMethodHS256, secret, "oct", claims), ok: true},
+		{name: "encryption key", token: sign(t, jwt.SigningMethodHS256, secret, "enc", claims)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.verify(tt.token)
+			if tt.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
+
+func TestLoadJWKSFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		key  map[string]string
+		err  string
+	}{
+		{name: "key type", key: map[string]string{"kty": "OKP", "kid": "okp"}, err: "unsupported key type"},
+		{name: "curve", key: map[string]string{"kty": "EC", "kid": "ec", "crv": "P-192"}, err: "unsupported curve"},
+		{name: "encoding", key: map[string]string{"kty": "oct", "kid": "oct", "k": "!"}, err: `kid "oct"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadJWKSFile(writeJWKS(t, tt.key))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: redis
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 11:05
+ */
+
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+// 默认的 redis 频道
+const defaultChannel = "pusher:backplane"
+
+// Redis 基于 redis pub/sub 的 backplane
+type Redis struct {
+	client  redis.UniversalClient
+	channel string
+	pubsub  *redis.PubSub
+}
+
+// NewRedis channel 为空时使用 pusher:backplane，同一集群的节点需使用相同的频道
+func NewRedis(client redis.UniversalClient, channel string) *Redis {
+	if channel == "" {
+		channel = defaultChannel
+	}
+	return &Redis{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (r *Redis) Publish(ctx context.Context, envelope *pusher.Envelope) error {
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, r.channel, raw).Err()
+}
+
+func (r *Redis) Subscribe(handler func(envelope *pusher.Envelope)) error {
+	pubsub := r.client.Subscribe(context.Background(), r.channel)
+	// 等待订阅确认，确保返回后不会丢失消息
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+		return err
+	}
+	r.pubsub = pubsub
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var envelope pusher.Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				logrus.Errorf("redis backplane decode error: %s", err.Error())
+				continue
+			}
+			handler(&envelope)
+		}
+	}()
+	logrus.Infof("Started Backplane: redis -> Channel: %s", r.channel)
+	return nil
+}
+
+func (r *Redis) Close() error {
+	if r.pubsub == nil {
+		return nil
+	}
+	return r.pubsub.Close()
+}
+
+var _ pusher.Backplane = (*Redis)(nil)
@@ -0,0 +1,66 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: binary
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 19:20
+ */
+
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// handleCodec 基于 ugorji codec 的 MessagePack、CBOR 编码
+type handleCodec struct {
+	name   string
+	handle codec.Handle
+}
+
+// MsgPack MessagePack 编码，以二进制帧发送
+func MsgPack() Codec {
+	handle := &codec.MsgpackHandle{}
+	handle.WriteExt = true
+	handle.RawToString = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return &handleCodec{name: NameMsgPack, handle: handle}
+}
+
+// CBOR CBOR 编码，以二进制帧发送
+func CBOR() Codec {
+	handle := &codec.CborHandle{}
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return &handleCodec{name: NameCBOR, handle: handle}
+}
+
+func (c *handleCodec) Name() string {
+	return c.name
+}
+
+func (c *handleCodec) Binary() bool {
+	return true
+}
+
+func (c *handleCodec) Encode(msg []byte) ([]byte, error) {
+	value, err := generic(msg)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, c.handle).Encode(value); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handleCodec) Decode(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := codec.NewDecoderBytes(data, c.handle).Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(stringKeys(value))
+}
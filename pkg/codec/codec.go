@@ -0,0 +1,121 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: codec
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 19:00
+ */
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Codec 消息编码，名称即客户端通过 Sec-WebSocket-Protocol 协商的子协议。
+// 服务端内部统一以 json 表示消息，Codec 负责与线上编码之间的转换
+type Codec interface {
+	Name() string
+	// Binary 是否以二进制帧发送
+	Binary() bool
+	// Encode 将 json 编码的消息转换为线上编码
+	Encode(msg []byte) ([]byte, error)
+	// Decode 将客户端请求转换为 json 编码
+	Decode(data []byte) ([]byte, error)
+}
+
+const (
+	NameJSON     = "json"
+	NameMsgPack  = "msgpack"
+	NameCBOR     = "cbor"
+	NameProtobuf = "protobuf"
+)
+
+type jsonCodec struct{}
+
+// JSON 默认编码，以文本帧发送
+func JSON() Codec {
+	return jsonCodec{}
+}
+
+func (jsonCodec) Name() string {
+	return NameJSON
+}
+
+func (jsonCodec) Binary() bool {
+	return false
+}
+
+func (jsonCodec) Encode(msg []byte) ([]byte, error) {
+	return msg, nil
+}
+
+func (jsonCodec) Decode(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// generic 将 json 解码为通用结构，整数解码为 int64，避免二进制编码中以浮点数传输
+func generic(msg []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(msg))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalize(value), nil
+}
+
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	}
+	return value
+}
+
+// stringKeys 二进制编码解码出的 map 键可能不是字符串，转换为 json 可编码的结构
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[toString(key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = stringKeys(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+	case []byte:
+		return string(v)
+	}
+	return value
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
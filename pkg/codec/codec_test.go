@@ -0,0 +1,101 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: codec_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 15:20
+ */
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var a, b interface{}
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatalf("invalid json %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("json = %s, want %s", got, want)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	msgs := []string{
+		`{"id":"r1","method":"subscribe","topics":["car"],"params":{"vin":"v1","limit":10,"ratio":0.5,"on":true,"none":null}}`,
+		`{"method":"publish","params":{"body":"` + strings.Repeat("x", 4096) + `"}}`,
+		`[{"code":1,"body":-3},{"code":1,"body":{"nested":[1,"a",{}]}}]`,
+	}
+	tests := []struct {
+		codec  Codec
+		binary bool
+	}{
+		{codec: JSON(), binary: false},
+		{codec: MsgPack(), binary: true},
+		{codec: CBOR(), binary: true},
+		{codec: Gzip(MsgPack(), 0), binary: true},
+		{codec: Gzip(CBOR(), 1), binary: true},
+		{codec: Zstd(MsgPack(), 1), binary: true},
+		{codec: Zstd(CBOR(), 0), binary: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codec.Name(), func(t *testing.T) {
+			if tt.codec.Binary() != tt.binary {
+				t.Fatalf("Binary() = %v, want %v", tt.codec.Binary(), tt.binary)
+			}
+			for _, msg := range msgs {
+				data, err := tt.codec.Encode([]byte(msg))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := tt.codec.Decode(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertJSON(t, got, msg)
+			}
+		})
+	}
+}
+
+func TestCompressedFrame(t *testing.T) {
+	codec := Gzip(MsgPack(), 0)
+	small, err := codec.Encode([]byte(`{"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small[0] != frameRaw {
+		t.Fatalf("small message flag = %d, want raw", small[0])
+	}
+	large, err := codec.Encode([]byte(`{"body":"` + strings.Repeat("x", DefaultMinCompressSize) + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if large[0] != frameCompressed {
+		t.Fatalf("large message flag = %d, want compressed", large[0])
+	}
+
+	bomb, err := gzipCompressor{}.compress(bytes.Repeat([]byte{0}, maxDecompressedSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, frame := range map[string][]byte{
+		"empty":        nil,
+		"unknown flag": {9, 0},
+		"oversized":    append([]byte{frameCompressed}, bomb...),
+	} {
+		if _, err := codec.Decode(frame); err == nil {
+			t.Fatalf("%s frame decoded", name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: compress
+ * @Version: 1.0.0
+ * @Date: 2026/10/22 09:30
+ */
+
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩编码帧的首字节，标识其后的内容是否经过压缩
+const (
+	frameRaw        byte = 0
+	frameCompressed byte = 1
+)
+
+// DefaultMinCompressSize 默认的压缩阈值，小于该大小的消息不压缩
+const DefaultMinCompressSize = 1024
+
+// maxDecompressedSize 客户端请求解压后的最大大小
+const maxDecompressedSize = 1 << 20
+
+// compressor 应用层压缩算法
+type compressor interface {
+	name() string
+	compress(data []byte) ([]byte, error)
+	decompress(data []byte) ([]byte, error)
+}
+
+// compressed 在二进制编码之上进行应用层压缩，子协议名为 <编码>+<算法>，如 msgpack+gzip。
+// 每帧首字节为 0 表示未压缩，为 1 表示其后内容经过压缩，客户端请求使用同样的格式
+type compressed struct {
+	codec      Codec
+	compressor compressor
+	minSize    int
+}
+
+// Gzip 对二进制编码进行 gzip 压缩，minSize 小于等于 0 时使用 DefaultMinCompressSize
+func Gzip(codec Codec, minSize int) Codec {
+	return newCompressed(codec, gzipCompressor{}, minSize)
+}
+
+// Zstd 对二进制编码进行 zstd 压缩，minSize 小于等于 0 时使用 DefaultMinCompressSize
+func Zstd(codec Codec, minSize int) Codec {
+	return newCompressed(codec, zstdCompressor{}, minSize)
+}
+
+func newCompressed(codec Codec, compressor compressor, minSize int) Codec {
+	if minSize <= 0 {
+		minSize = DefaultMinCompressSize
+	}
+	return &compressed{codec: codec, compressor: compressor, minSize: minSize}
+}
+
+func (c *compressed) Name() string {
+	return c.codec.Name() + "+" + c.compressor.name()
+}
+
+func (c *compressed) Binary() bool {
+	return true
+}
+
+func (c *compressed) Encode(msg []byte) ([]byte, error) {
+	data, err := c.codec.Encode(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.minSize {
+		return append([]byte{frameRaw}, data...), nil
+	}
+	packed, err := c.compressor.compress(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{frameCompressed}, packed...), nil
+}
+
+func (c *compressed) Decode(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s: empty frame", c.Name())
+	}
+	switch data[0] {
+	case frameRaw:
+		return c.codec.Decode(data[1:])
+	case frameCompressed:
+		unpacked, err := c.compressor.decompress(data[1:])
+		if err != nil {
+			return nil, err
+		}
+		return c.codec.Decode(unpacked)
+	}
+	return nil, fmt.Errorf("%s: unknown frame flag %d", c.Name(), data[0])
+}
+
+type gzipCompressor struct{}
+
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+func (gzipCompressor) name() string {
+	return "gzip"
+}
+
+func (gzipCompressor) compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(writer)
+
+	writer.Reset(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readLimited(reader)
+}
+
+// readLimited 读取解压内容，超出 maxDecompressedSize 时返回错误
+func readLimited(reader io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed request exceeds %d bytes", maxDecompressedSize)
+	}
+	return data, nil
+}
+
+type zstdCompressor struct{}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+// zstdCodecs 编解码器可并发使用，全局共享
+func zstdCodecs() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+	})
+	return zstdEncoder, zstdDecoder
+}
+
+func (zstdCompressor) name() string {
+	return "zstd"
+}
+
+func (zstdCompressor) compress(data []byte) ([]byte, error) {
+	encoder, _ := zstdCodecs()
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (zstdCompressor) decompress(data []byte) ([]byte, error) {
+	_, decoder := zstdCodecs()
+	return decoder.DecodeAll(data, nil)
+}
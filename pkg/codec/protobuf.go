@@ -0,0 +1,224 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: protobuf
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 19:40
+ */
+
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// 消息信封字段编号，与 proto/pusher.proto 中的 Envelope 一致
+const (
+	envelopeID        protowire.Number = 1
+	envelopeCode      protowire.Number = 2
+	envelopeType      protowire.Number = 3
+	envelopeName      protowire.Number = 4
+	envelopeSeq       protowire.Number = 5
+	envelopeBody      protowire.Number = 6
+	envelopeError     protowire.Number = 7
+	envelopeTimestamp protowire.Number = 8
+	envelopeExtra     protowire.Number = 9
+	envelopeBatch     protowire.Number = 10
+	envelopeErrCode   protowire.Number = 11
+)
+
+// 客户端请求字段编号，与 proto/pusher.proto 中的 Request 一致
+const (
+	requestID     protowire.Number = 1
+	requestMethod protowire.Number = 2
+	requestTopics protowire.Number = 3
+	requestParams protowire.Number = 4
+)
+
+type protobufCodec struct{}
+
+// Protobuf Protobuf 编码，以二进制帧发送，消息体为 google.protobuf.Value
+func Protobuf() Codec {
+	return protobufCodec{}
+}
+
+func (protobufCodec) Name() string {
+	return NameProtobuf
+}
+
+func (protobufCodec) Binary() bool {
+	return true
+}
+
+// Encode 编码为 Envelope，数组形式的批量消息编码为 type 为 batch 的 Envelope，消息位于 batch 字段
+func (protobufCodec) Encode(msg []byte) ([]byte, error) {
+	value, err := generic(msg)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return encodeEnvelope(value)
+	}
+	out := appendString(nil, envelopeType, "batch")
+	for _, item := range items {
+		envelope, err := encodeEnvelope(item)
+		if err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, envelopeBatch, protowire.BytesType)
+		out = protowire.AppendBytes(out, envelope)
+	}
+	return out, nil
+}
+
+func encodeEnvelope(value interface{}) ([]byte, error) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("protobuf: message is not an object")
+	}
+
+	var err error
+	var out []byte
+	extra := make(map[string]interface{})
+	for key, field := range fields {
+		switch key {
+		case "id":
+			out = appendString(out, envelopeID, field)
+		case "type":
+			out = appendString(out, envelopeType, field)
+		case "name":
+			out = appendString(out, envelopeName, field)
+		case "error":
+			out = appendString(out, envelopeError, field)
+		case "code":
+			out = appendVarint(out, envelopeCode, field)
+		case "errcode":
+			out = appendVarint(out, envelopeErrCode, field)
+		case "seq":
+			out = appendVarint(out, envelopeSeq, field)
+		case "timestamp":
+			out = appendVarint(out, envelopeTimestamp, field)
+		case "body":
+			if field == nil {
+				continue
+			}
+			if out, err = appendValue(out, envelopeBody, field); err != nil {
+				return nil, err
+			}
+		default:
+			extra[key] = field
+		}
+	}
+	if len(extra) > 0 {
+		if out, err = appendStruct(out, envelopeExtra, extra); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func appendString(out []byte, num protowire.Number, value interface{}) []byte {
+	s, _ := value.(string)
+	if s == "" {
+		return out
+	}
+	out = protowire.AppendTag(out, num, protowire.BytesType)
+	return protowire.AppendString(out, s)
+}
+
+func appendVarint(out []byte, num protowire.Number, value interface{}) []byte {
+	var v int64
+	switch n := value.(type) {
+	case int64:
+		v = n
+	case float64:
+		v = int64(n)
+	}
+	if v == 0 {
+		return out
+	}
+	out = protowire.AppendTag(out, num, protowire.VarintType)
+	return protowire.AppendVarint(out, uint64(v))
+}
+
+func appendValue(out []byte, num protowire.Number, value interface{}) ([]byte, error) {
+	v, err := structpb.NewValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return appendMessage(out, num, v)
+}
+
+// appendStruct 写入 google.protobuf.Struct 字段
+func appendStruct(out []byte, num protowire.Number, fields map[string]interface{}) ([]byte, error) {
+	v, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	return appendMessage(out, num, v)
+}
+
+func appendMessage(out []byte, num protowire.Number, m proto.Message) ([]byte, error) {
+	raw, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	out = protowire.AppendTag(out, num, protowire.BytesType)
+	return protowire.AppendBytes(out, raw), nil
+}
+
+// request 与 pusher.ClientRequest 的 json 结构一致
+type request struct {
+	ID     string          `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Topics []string        `json:"topics,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+func (protobufCodec) Decode(data []byte) ([]byte, error) {
+	var req request
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, data); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		field, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch num {
+		case requestID:
+			req.ID = string(field)
+		case requestMethod:
+			req.Method = string(field)
+		case requestTopics:
+			req.Topics = append(req.Topics, string(field))
+		case requestParams:
+			value := &structpb.Value{}
+			if err := proto.Unmarshal(field, value); err != nil {
+				return nil, err
+			}
+			params, err := value.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			req.Params = params
+		}
+	}
+	return json.Marshal(req)
+}
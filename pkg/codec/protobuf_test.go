@@ -0,0 +1,228 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: protobuf_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 15:00
+ */
+
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// envelope 按 proto/pusher.proto 中 Envelope 声明的字段类型解码，校验编码结果与 schema 一致
+type envelope struct {
+	ID        string
+	Code      int32
+	Type      string
+	Name      string
+	Seq       uint64
+	Body      *structpb.Value
+	Error     string
+	Timestamp int64
+	Extra     *structpb.Struct
+	Batch     []*envelope
+	ErrCode   int32
+}
+
+func decodeEnvelope(data []byte) (*envelope, error) {
+	e := &envelope{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			switch num {
+			case envelopeCode:
+				e.Code = int32(v)
+			case envelopeSeq:
+				e.Seq = v
+			case envelopeTimestamp:
+				e.Timestamp = int64(v)
+			case envelopeErrCode:
+				e.ErrCode = int32(v)
+			default:
+				return nil, fmt.Errorf("field %d is not a varint", num)
+			}
+			continue
+		}
+		if typ != protowire.BytesType {
+			return nil, fmt.Errorf("field %d has wire type %d", num, typ)
+		}
+		field, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		var message proto.Message
+		switch num {
+		case envelopeID:
+			e.ID = string(field)
+		case envelopeType:
+			e.Type = string(field)
+		case envelopeName:
+			e.Name = string(field)
+		case envelopeError:
+			e.Error = string(field)
+		case envelopeBody:
+			e.Body = &structpb.Value{}
+			message = e.Body
+		case envelopeExtra:
+			e.Extra = &structpb.Struct{}
+			message = e.Extra
+		case envelopeBatch:
+			item, err := decodeEnvelope(field)
+			if err != nil {
+				return nil, err
+			}
+			e.Batch = append(e.Batch, item)
+		default:
+			return nil, fmt.Errorf("unknown field %d", num)
+		}
+		if message == nil {
+			continue
+		}
+		if err := proto.Unmarshal(field, message); err != nil {
+			return nil, err
+		}
+		// 类型不符时字段会落入未知字段而不是报错
+		if unknown := message.ProtoReflect().GetUnknown(); len(unknown) > 0 {
+			return nil, fmt.Errorf("field %d does not match its declared type", num)
+		}
+	}
+	return e, nil
+}
+
+func TestProtobufEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want *envelope
+	}{
+		{
+			name: "data",
+			msg:  `{"code":1,"type":"data","name":"Car","seq":42,"timestamp":1700000000000,"body":{"speed":60.5,"tags":["a"]},"delta":true,"chunk":{"index":1}}`,
+			want: &envelope{Code: 1, Type: "data", Name: "Car", Seq: 42, Timestamp: 1700000000000},
+		},
+		{
+			name: "error",
+			msg:  `{"id":"r1","code":0,"errcode":4003,"type":"method","error":"forbidden"}`,
+			want: &envelope{ID: "r1", Type: "method", Error: "forbidden", ErrCode: 4003},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Protobuf().Encode([]byte(tt.msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decodeEnvelope(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEnvelope(t, got, tt.want, tt.msg)
+		})
+	}
+}
+
+func TestProtobufEncodeBatch(t *testing.T) {
+	items := []string{
+		`{"code":1,"type":"data","name":"Car","body":1,"ack":true}`,
+		`{"code":1,"type":"data","name":"Bus","body":"x"}`,
+	}
+	data, err := Protobuf().Encode([]byte("[" + items[0] + "," + items[1] + "]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "batch" || len(got.Batch) != len(items) {
+		t.Fatalf("batch = %+v", got)
+	}
+	for i, item := range items {
+		var fields map[string]interface{}
+		_ = json.Unmarshal([]byte(item), &fields)
+		assertEnvelope(t, got.Batch[i], &envelope{Code: 1, Type: "data", Name: fields["name"].(string)}, item)
+	}
+
+	if _, err := Protobuf().Encode([]byte(`"text"`)); err == nil {
+		t.Fatal("non-object message encoded")
+	}
+}
+
+// assertEnvelope 比较标量字段，body 与 extra 与原始 json 中的对应内容比较
+func assertEnvelope(t *testing.T, got, want *envelope, msg string) {
+	t.Helper()
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(msg), &fields); err != nil {
+		t.Fatal(err)
+	}
+	wantExtra := make(map[string]interface{})
+	for key, value := range fields {
+		switch key {
+		case "id", "code", "errcode", "type", "name", "seq", "timestamp", "error":
+		case "body":
+			if got.Body == nil || !reflect.DeepEqual(got.Body.AsInterface(), value) {
+				t.Fatalf("body = %v, want %v", got.Body, value)
+			}
+		default:
+			wantExtra[key] = value
+		}
+	}
+	if len(wantExtra) == 0 {
+		if got.Extra != nil {
+			t.Fatalf("extra = %v, want none", got.Extra)
+		}
+	} else if got.Extra == nil || !reflect.DeepEqual(got.Extra.AsMap(), wantExtra) {
+		t.Fatalf("extra = %v, want %v", got.Extra, wantExtra)
+	}
+	got.Body, got.Extra, got.Batch = nil, nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("envelope = %+v, want %+v", got, want)
+	}
+}
+
+func TestProtobufDecode(t *testing.T) {
+	params, err := structpb.NewValue(map[string]interface{}{"vin": "v1", "limit": 10.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := proto.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	data = appendString(data, requestID, "r1")
+	data = appendString(data, requestMethod, "subscribe")
+	data = appendString(data, requestTopics, "car")
+	data = appendString(data, requestTopics, "bus")
+	data = protowire.AppendTag(data, requestParams, protowire.BytesType)
+	data = protowire.AppendBytes(data, raw)
+	// 未知字段跳过
+	data = protowire.AppendTag(data, 15, protowire.VarintType)
+	data = protowire.AppendVarint(data, 1)
+
+	got, err := Protobuf().Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, got, `{"id":"r1","method":"subscribe","topics":["car","bus"],"params":{"vin":"v1","limit":10}}`)
+}
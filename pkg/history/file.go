@@ -0,0 +1,375 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: file
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 17:00
+ */
+
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+const (
+	defaultSegmentSize = 8 << 20
+	segmentExt         = ".log"
+)
+
+type FileOptions struct {
+	// MaxAge 数据保留时间，按段淘汰，段内最新数据超过 MaxAge 时删除整段，0 表示不限制
+	MaxAge time.Duration
+	// MaxBytes 单个主题保留的最大字节数，超出时删除最旧的段，0 表示不限制
+	MaxBytes int64
+	// SegmentSize 单个段文件大小，默认 8MB
+	SegmentSize int64
+	// Decoder 还原数据，默认还原为 json.RawMessage
+	Decoder pusher.EventDecoder
+}
+
+// record 段文件中的一行
+type record struct {
+	Seq    uint64          `json:"seq"`
+	Time   int64           `json:"time"`
+	ID     string          `json:"id"`
+	Source string          `json:"source"`
+	Raw    json.RawMessage `json:"raw"`
+}
+
+type segment struct {
+	path  string
+	first uint64
+	last  uint64
+	size  int64
+	// 段内最新数据的时间
+	latest time.Time
+}
+
+// topicLog 主题的段索引，由 mutex 保护，不同主题的读写互不阻塞
+type topicLog struct {
+	mutex    sync.Mutex
+	dir      string
+	seq      uint64
+	segments []*segment
+	active   *os.File
+}
+
+// FileStore 磁盘历史存储，每个主题一个目录，数据以 json 行追加写入段文件，段文件以首条数据的序号命名
+//
+//	store, err := history.NewFileStore("data/history", history.FileOptions{MaxAge: 24 * time.Hour})
+//	hub.SetHistoryStore(store)
+type FileStore struct {
+	// mutex 仅保护 topics
+	mutex   sync.Mutex
+	dir     string
+	options FileOptions
+	topics  map[string]*topicLog
+}
+
+// NewFileStore 打开目录并重建各主题的段索引
+func NewFileStore(dir string, options FileOptions) (*FileStore, error) {
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	store := &FileStore{
+		dir:     dir,
+		options: options,
+		topics:  make(map[string]*topicLog),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		topic, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		log, err := openTopic(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("open history %s: %s", topic, err.Error())
+		}
+		store.topics[topic] = log
+	}
+	return store, nil
+}
+
+func openTopic(dir string) (*topicLog, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	log := &topicLog{dir: dir}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &segment{path: filepath.Join(dir, name), first: first, last: first - 1}
+		if err := scanSegment(seg.path, func(r *record, size int) bool {
+			seg.last = r.Seq
+			seg.size += int64(size)
+			seg.latest = time.Unix(0, r.Time)
+			return true
+		}); err != nil {
+			return nil, err
+		}
+		log.segments = append(log.segments, seg)
+	}
+	sort.Slice(log.segments, func(i, j int) bool {
+		return log.segments[i].first < log.segments[j].first
+	})
+	if n := len(log.segments); n > 0 {
+		log.seq = log.segments[n-1].last
+	}
+	return log, nil
+}
+
+// scanSegment 顺序读取段文件，fn 返回 false 时停止，末尾不完整的行忽略
+func scanSegment(path string, fn func(r *record, size int) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil
+		}
+		var r record
+		if json.Unmarshal(line, &r) != nil {
+			continue
+		}
+		if !fn(&r, len(line)) {
+			return nil
+		}
+	}
+}
+
+func (s *FileStore) topic(topic string, create bool) (*topicLog, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	log, exists := s.topics[topic]
+	if exists || !create {
+		return log, nil
+	}
+	log = &topicLog{dir: filepath.Join(s.dir, url.PathEscape(topic))}
+	if err := os.MkdirAll(log.dir, 0o755); err != nil {
+		return nil, err
+	}
+	s.topics[topic] = log
+	return log, nil
+}
+
+func (s *FileStore) Append(topic string, data pusher.Data) (uint64, error) {
+	raw, err := json.Marshal(data.Raw())
+	if err != nil {
+		return 0, err
+	}
+	log, err := s.topic(topic, true)
+	if err != nil {
+		return 0, err
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	now := time.Now()
+	r := record{
+		Seq:    log.seq + 1,
+		Time:   now.UnixNano(),
+		ID:     data.ID(),
+		Source: data.Metadata().Source(),
+		Raw:    raw,
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return 0, err
+	}
+	line = append(line, '\n')
+
+	seg, err := s.writable(log, r.Seq, int64(len(line)))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := log.active.Write(line); err != nil {
+		return 0, err
+	}
+	log.seq = r.Seq
+	seg.last = r.Seq
+	seg.size += int64(len(line))
+	seg.latest = now
+	s.retain(log)
+	return r.Seq, nil
+}
+
+// writable 返回可写入的段，当前段写满或尚未打开时切换到新段
+func (s *FileStore) writable(log *topicLog, seq uint64, size int64) (*segment, error) {
+	n := len(log.segments)
+	if n > 0 && log.active != nil && log.segments[n-1].size+size <= s.options.SegmentSize {
+		return log.segments[n-1], nil
+	}
+	if log.active != nil {
+		_ = log.active.Close()
+		log.active = nil
+	}
+	seg := &segment{
+		path:  filepath.Join(log.dir, fmt.Sprintf("%020d%s", seq, segmentExt)),
+		first: seq,
+		last:  seq - 1,
+	}
+	file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	log.active = file
+	log.segments = append(log.segments, seg)
+	return seg, nil
+}
+
+// retain 按 MaxAge、MaxBytes 删除最旧的段，正在写入的段不删除
+func (s *FileStore) retain(log *topicLog) {
+	var total int64
+	for _, seg := range log.segments {
+		total += seg.size
+	}
+	deadline := time.Now().Add(-s.options.MaxAge)
+	for len(log.segments) > 1 {
+		oldest := log.segments[0]
+		expired := s.options.MaxAge > 0 && oldest.latest.Before(deadline)
+		oversize := s.options.MaxBytes > 0 && total > s.options.MaxBytes
+		if !expired && !oversize {
+			break
+		}
+		_ = os.Remove(oldest.path)
+		total -= oldest.size
+		log.segments = log.segments[1:]
+	}
+}
+
+// Query 持有主题锁复制段索引后读取段文件，读取期间不阻塞写入
+func (s *FileStore) Query(topic string, query pusher.HistoryQuery) ([]pusher.HistoryEntry, error) {
+	log, _ := s.topic(topic, false)
+	if log == nil {
+		return nil, nil
+	}
+	log.mutex.Lock()
+	s.retain(log)
+	segments := make([]segment, 0, len(log.segments))
+	for _, seg := range log.segments {
+		segments = append(segments, *seg)
+	}
+	log.mutex.Unlock()
+
+	var entries []pusher.HistoryEntry
+	for _, seg := range segments {
+		if seg.last <= query.After || seg.last < seg.first || seg.latest.Before(query.Since) {
+			continue
+		}
+		last := seg.last
+		var decodeErr error
+		err := scanSegment(seg.path, func(r *record, _ int) bool {
+			// 复制索引之后追加的数据不返回
+			if r.Seq > last {
+				return false
+			}
+			at := time.Unix(0, r.Time)
+			if r.Seq <= query.After || at.Before(query.Since) {
+				return true
+			}
+			data, err := s.decode(r)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			pusher.Route(data).SetTopic(topic)
+			entries = append(entries, pusher.HistoryEntry{Seq: r.Seq, Topic: topic, Time: at, Data: data})
+			if query.Limit > 0 && len(entries) > query.Limit {
+				entries = entries[1:]
+			}
+			return true
+		})
+		// 读取期间被淘汰的段
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+	}
+	return entries, nil
+}
+
+func (s *FileStore) decode(r *record) (pusher.Data, error) {
+	var value interface{} = r.Raw
+	if s.options.Decoder != nil {
+		var err error
+		if value, err = s.options.Decoder(r.Source, r.Raw); err != nil {
+			return nil, err
+		}
+	}
+	return pusher.NewDataWithID(r.ID, r.Source, value), nil
+}
+
+func (s *FileStore) Bounds(topic string) (uint64, uint64, error) {
+	log, _ := s.topic(topic, false)
+	if log == nil {
+		return 0, 0, nil
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	s.retain(log)
+	for _, seg := range log.segments {
+		if seg.last >= seg.first {
+			return seg.first, log.seq, nil
+		}
+	}
+	return 0, log.seq, nil
+}
+
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, log := range s.topics {
+		log.mutex.Lock()
+		if log.active != nil {
+			_ = log.active.Close()
+			log.active = nil
+		}
+		log.mutex.Unlock()
+	}
+	return nil
+}
+
+var _ pusher.HistoryStore = (*FileStore)(nil)
@@ -0,0 +1,99 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: file_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 13:10
+ */
+
+package history
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+func TestFileStoreQuery(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir, FileOptions{SegmentSize: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		seq, err := store.Append("car", pusher.NewData("test", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != uint64(i) {
+			t.Fatalf("seq = %d, want %d", seq, i)
+		}
+	}
+
+	entries, err := store.Query("car", pusher.HistoryQuery{After: 4, Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Seq != 8 || entries[2].Seq != 10 {
+		t.Fatalf("entries = %+v, want seq 8..10", entries)
+	}
+	if raw := string(entries[0].Data.Raw().(json.RawMessage)); raw != "8" {
+		t.Fatalf("raw = %s, want 8", raw)
+	}
+	_ = store.Close()
+
+	// 重新打开后恢复序号
+	store, err = NewFileStore(dir, FileOptions{SegmentSize: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if first, last, _ := store.Bounds("car"); first != 1 || last != 10 {
+		t.Fatalf("bounds = %d..%d, want 1..10", first, last)
+	}
+	if seq, _ := store.Append("car", pusher.NewData("test", 11)); seq != 11 {
+		t.Fatalf("seq after reopen = %d, want 11", seq)
+	}
+}
+
+func TestFileStoreConcurrentQuery(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), FileOptions{SegmentSize: 512, MaxBytes: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var wg sync.WaitGroup
+	for _, topic := range []string{"car", "alarm"} {
+		wg.Add(2)
+		go func(topic string) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, err := store.Append(topic, pusher.NewData("test", strconv.Itoa(i))); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(topic)
+		go func(topic string) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				entries, err := store.Query(topic, pusher.HistoryQuery{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for j := 1; j < len(entries); j++ {
+					if entries[j].Seq != entries[j-1].Seq+1 {
+						t.Errorf("%s entries not contiguous: %d after %d", topic, entries[j].Seq, entries[j-1].Seq)
+						return
+					}
+				}
+			}
+		}(topic)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,257 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: json
+ * @Version: 1.0.0
+ * @Date: 2026/10/22 14:20
+ */
+
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation JSON Patch（RFC 6902）操作
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON remove 操作不携带 value，其他操作的 value 可以为 null
+func (op Operation) MarshalJSON() ([]byte, error) {
+	if op.Op == OpRemove {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path})
+	}
+	type operation Operation
+	return json.Marshal(operation(op))
+}
+
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpTest    = "test"
+)
+
+// Diff 生成从 from 到 to 的 JSON Patch，两者为 json 通用结构，只使用 add、remove 及 replace 操作。
+// 数组按下标逐项比较，长度变化时在末尾追加或删除
+func Diff(from, to interface{}) []Operation {
+	return diff(nil, "", from, to)
+}
+
+func diff(ops []Operation, path string, from, to interface{}) []Operation {
+	if reflect.DeepEqual(from, to) {
+		return ops
+	}
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, key := range sortedKeys(f) {
+			if _, exists := t[key]; !exists {
+				ops = append(ops, Operation{Op: OpRemove, Path: path + "/" + escape(key)})
+			}
+		}
+		for _, key := range sortedKeys(t) {
+			old, exists := f[key]
+			if !exists {
+				ops = append(ops, Operation{Op: OpAdd, Path: path + "/" + escape(key), Value: t[key]})
+				continue
+			}
+			ops = diff(ops, path+"/"+escape(key), old, t[key])
+		}
+		return ops
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+		common := len(f)
+		if len(t) < common {
+			common = len(t)
+		}
+		for i := 0; i < common; i++ {
+			ops = diff(ops, path+"/"+strconv.Itoa(i), f[i], t[i])
+		}
+		for i := len(f) - 1; i >= len(t); i-- {
+			ops = append(ops, Operation{Op: OpRemove, Path: path + "/" + strconv.Itoa(i)})
+		}
+		for i := len(f); i < len(t); i++ {
+			ops = append(ops, Operation{Op: OpAdd, Path: path + "/" + strconv.Itoa(i), Value: t[i]})
+		}
+		return ops
+	}
+	// 类型不同或为标量时整体替换，根路径为空字符串
+	return append(ops, Operation{Op: OpReplace, Path: path, Value: to})
+}
+
+// Apply 将 JSON Patch 应用到 target，支持 add、remove、replace 及 test 操作，target 可能被修改
+func Apply(target interface{}, ops []Operation) (interface{}, error) {
+	for _, op := range ops {
+		var err error
+		if target, err = apply(target, op); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+func apply(target interface{}, op Operation) (interface{}, error) {
+	if op.Path == "" {
+		switch op.Op {
+		case OpAdd, OpReplace:
+			return op.Value, nil
+		case OpTest:
+			if !reflect.DeepEqual(target, op.Value) {
+				return nil, fmt.Errorf("test failed at root")
+			}
+			return target, nil
+		}
+		return nil, fmt.Errorf("unsupported op %s at root", op.Op)
+	}
+	tokens := strings.Split(op.Path[1:], "/")
+	parent := target
+	for _, token := range tokens[:len(tokens)-1] {
+		child, err := get(parent, unescape(token))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", op.Path, err.Error())
+		}
+		parent = child
+	}
+	last := unescape(tokens[len(tokens)-1])
+	updated, err := update(parent, last, op)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", op.Path, err.Error())
+	}
+	if len(tokens) == 1 {
+		return updated, nil
+	}
+	// 数组长度变化时需要写回上层
+	return set(target, tokens[:len(tokens)-1], updated)
+}
+
+func get(node interface{}, token string) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		child, exists := v[token]
+		if !exists {
+			return nil, fmt.Errorf("key %s not found", token)
+		}
+		return child, nil
+	case []interface{}:
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, fmt.Errorf("index %s out of range", token)
+		}
+		return v[i], nil
+	}
+	return nil, fmt.Errorf("cannot traverse %T", node)
+}
+
+func set(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token := unescape(tokens[0])
+	child, err := get(node, token)
+	if err != nil {
+		return nil, err
+	}
+	child, err = set(child, tokens[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		v[token] = child
+	case []interface{}:
+		i, _ := strconv.Atoi(token)
+		v[i] = child
+	}
+	return node, nil
+}
+
+func update(node interface{}, token string, op Operation) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		_, exists := v[token]
+		switch op.Op {
+		case OpAdd:
+			v[token] = op.Value
+		case OpReplace:
+			if !exists {
+				return nil, fmt.Errorf("key %s not found", token)
+			}
+			v[token] = op.Value
+		case OpRemove:
+			if !exists {
+				return nil, fmt.Errorf("key %s not found", token)
+			}
+			delete(v, token)
+		case OpTest:
+			if !exists || !reflect.DeepEqual(v[token], op.Value) {
+				return nil, fmt.Errorf("test failed")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported op %s", op.Op)
+		}
+		return v, nil
+	case []interface{}:
+		if token == "-" && op.Op == OpAdd {
+			return append(v, op.Value), nil
+		}
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i > len(v) || (i == len(v) && op.Op != OpAdd) {
+			return nil, fmt.Errorf("index %s out of range", token)
+		}
+		switch op.Op {
+		case OpAdd:
+			v = append(v, nil)
+			copy(v[i+1:], v[i:])
+			v[i] = op.Value
+		case OpReplace:
+			v[i] = op.Value
+		case OpRemove:
+			v = append(v[:i], v[i+1:]...)
+		case OpTest:
+			if !reflect.DeepEqual(v[i], op.Value) {
+				return nil, fmt.Errorf("test failed")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported op %s", op.Op)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot update %T", node)
+}
+
+// escape 按 RFC 6901 转义 JSON Pointer 中的 ~ 及 /
+func escape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// sortedKeys 按键排序，保证生成的操作顺序稳定
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
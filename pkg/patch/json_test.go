@@ -0,0 +1,102 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: json_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 16:00
+ */
+
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return value
+}
+
+func TestDiffApply(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		ops  int
+	}{
+		{name: "equal", from: `{"a":1}`, to: `{"a":1}`, ops: 0},
+		{name: "scalar", from: `{"a":1,"b":"x"}`, to: `{"a":2,"b":"x"}`, ops: 1},
+		{name: "add and remove", from: `{"a":1,"b":2}`, to: `{"b":2,"c":{"d":null}}`, ops: 2},
+		{name: "escaped keys", from: `{"a/b":1,"m~n":{"x~/y":1,"~1":2}}`, to: `{"a/b":2,"m~n":{"x~/y":3},"/":{"~0":[]}}`, ops: 4},
+		{name: "array grow", from: `{"list":[1,2]}`, to: `{"list":[1,3,4,5]}`, ops: 3},
+		{name: "array shrink", from: `{"list":[1,2,3,4]}`, to: `{"list":[0]}`, ops: 4},
+		{name: "array to empty", from: `[{"a":1},{"b":2}]`, to: `[]`, ops: 2},
+		{name: "nested array objects", from: `{"cars":[{"vin":"a","speed":1},{"vin":"b"}]}`, to: `{"cars":[{"vin":"a","speed":2},{"vin":"b","tags":["x"]}]}`, ops: 2},
+		{name: "nested type change", from: `{"a":{"b":1}}`, to: `{"a":[1]}`, ops: 1},
+		{name: "root object to array", from: `{"a":1}`, to: `[1,2]`, ops: 1},
+		{name: "root scalar", from: `"x"`, to: `3`, ops: 1},
+		{name: "root from null", from: `null`, to: `{"a":1}`, ops: 1},
+		{name: "root to null", from: `{"a":1}`, to: `null`, ops: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := Diff(decode(t, tt.from), decode(t, tt.to))
+			if len(ops) != tt.ops {
+				t.Fatalf("Diff = %+v, want %d operations", ops, tt.ops)
+			}
+			// 补丁经 json 编解码后应用，与客户端收到的一致
+			raw, err := json.Marshal(ops)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded []Operation
+			if err := json.Unmarshal(raw, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Apply(decode(t, tt.from), decoded)
+			if err != nil {
+				t.Fatalf("Apply %s: %v", raw, err)
+			}
+			if want := decode(t, tt.to); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Apply(from, %s) = %v, want %v", raw, got, want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		ops    []Operation
+	}{
+		{name: "missing key", target: `{"a":1}`, ops: []Operation{{Op: OpReplace, Path: "/b", Value: 1}}},
+		{name: "missing parent", target: `{"a":1}`, ops: []Operation{{Op: OpAdd, Path: "/b/c", Value: 1}}},
+		{name: "index out of range", target: `[1]`, ops: []Operation{{Op: OpRemove, Path: "/1"}}},
+		{name: "test failed", target: `{"a":1}`, ops: []Operation{{Op: OpTest, Path: "/a", Value: 2.0}}},
+		{name: "unsupported op", target: `{"a":1}`, ops: []Operation{{Op: "move", Path: "/a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Apply(decode(t, tt.target), tt.ops); err == nil {
+				t.Fatalf("Apply(%s, %+v) succeeded", tt.target, tt.ops)
+			}
+		})
+	}
+}
+
+func TestOperationMarshal(t *testing.T) {
+	raw, err := json.Marshal([]Operation{{Op: OpRemove, Path: "/a"}, {Op: OpReplace, Path: "/b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"op":"remove","path":"/a"},{"op":"replace","path":"/b","value":null}]`; string(raw) != want {
+		t.Fatalf("Marshal = %s, want %s", raw, want)
+	}
+}
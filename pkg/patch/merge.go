@@ -0,0 +1,88 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: merge
+ * @Version: 1.0.0
+ * @Date: 2026/10/22 14:00
+ */
+
+package patch
+
+import (
+	"reflect"
+)
+
+// Merge 生成从 from 到 to 的 JSON Merge Patch（RFC 7396），两者为 json 通用结构。
+// Merge Patch 以 null 表示删除字段，to 中值为 null 的字段应用后会被删除；数组整体替换
+func Merge(from, to interface{}) interface{} {
+	fromObject, ok := from.(map[string]interface{})
+	if !ok {
+		return to
+	}
+	toObject, ok := to.(map[string]interface{})
+	if !ok {
+		return to
+	}
+	patch := make(map[string]interface{})
+	for key := range fromObject {
+		if _, exists := toObject[key]; !exists {
+			patch[key] = nil
+		}
+	}
+	for key, value := range toObject {
+		old, exists := fromObject[key]
+		if !exists {
+			patch[key] = value
+			continue
+		}
+		if reflect.DeepEqual(old, value) {
+			continue
+		}
+		_, oldObject := old.(map[string]interface{})
+		_, newObject := value.(map[string]interface{})
+		if oldObject && newObject {
+			patch[key] = Merge(old, value)
+			continue
+		}
+		patch[key] = value
+	}
+	return patch
+}
+
+// Mergeable 判断 to 能否用 Merge Patch 准确表示：对象中值为 null 的字段应用后会被删除，
+// 此时应改为发送完整数据。数组整体替换，其中的 null 不受影响
+func Mergeable(to interface{}) bool {
+	object, ok := to.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	for _, value := range object {
+		if value == nil || !Mergeable(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyMerge 将 JSON Merge Patch 应用到 target，返回新的结构，target 不会被修改
+func ApplyMerge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	result := make(map[string]interface{}, len(targetObject))
+	if ok {
+		for key, value := range targetObject {
+			result[key] = value
+		}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = ApplyMerge(result[key], value)
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: merge_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 16:10
+ */
+
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		mergeable bool
+	}{
+		{name: "equal", from: `{"a":1}`, to: `{"a":1}`, mergeable: true},
+		{name: "add and remove", from: `{"a":1,"b":2}`, to: `{"b":3,"c":{"d":"x"}}`, mergeable: true},
+		{name: "nested", from: `{"a":{"b":1,"c":2}}`, to: `{"a":{"b":1,"d":[1,2]}}`, mergeable: true},
+		{name: "escaped keys", from: `{"a/b":1,"m~n":{"x":1}}`, to: `{"a/b":2,"m~n":{"y":1}}`, mergeable: true},
+		{name: "array replaced", from: `{"list":[1,2,3]}`, to: `{"list":[1]}`, mergeable: true},
+		{name: "null inside array", from: `{"list":[1]}`, to: `{"list":[null,{"a":null}]}`, mergeable: true},
+		{name: "root replaced", from: `{"a":1}`, to: `[1,2]`, mergeable: true},
+		{name: "root from array", from: `[1]`, to: `{"a":1}`, mergeable: true},
+		{name: "null field", from: `{"a":1,"b":2}`, to: `{"a":1,"b":null}`, mergeable: false},
+		{name: "nested null field", from: `{"a":{"b":1}}`, to: `{"a":{"b":1,"c":null}}`, mergeable: false},
+		{name: "new object with null", from: `{}`, to: `{"a":{"b":null}}`, mergeable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := decode(t, tt.from), decode(t, tt.to)
+			if got := Mergeable(to); got != tt.mergeable {
+				t.Fatalf("Mergeable(%s) = %v, want %v", tt.to, got, tt.mergeable)
+			}
+			raw, err := json.Marshal(Merge(from, to))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ApplyMerge(from, decode(t, string(raw)))
+			// 含 null 字段的数据无法用 Merge Patch 还原，调用方需推送全量
+			if reflect.DeepEqual(got, to) != tt.mergeable {
+				t.Fatalf("ApplyMerge(from, %s) = %v, want %v restored %v", raw, got, to, tt.mergeable)
+			}
+			if !reflect.DeepEqual(from, decode(t, tt.from)) {
+				t.Fatalf("ApplyMerge modified target: %v", from)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: config
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 11:30
+ */
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Condition 字段条件，op 支持 eq、ne、gt、gte、lt、lte、in、exists、missing
+//
+//	{"field":"speed","op":"lt","value":1}
+type Condition struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// Predicate 转换为 Drop 使用的判断函数，数据不是对象时不满足条件
+func (c Condition) Predicate() (Predicate, error) {
+	compare := func(fn func(a, b float64) bool) func(interface{}) bool {
+		return func(value interface{}) bool {
+			x, ok := toFloat(value)
+			y, ok2 := toFloat(c.Value)
+			return ok && ok2 && fn(x, y)
+		}
+	}
+	var match func(value interface{}) bool
+	switch c.Op {
+	case "eq":
+		match = func(value interface{}) bool { return equal(value, c.Value) }
+	case "ne":
+		match = func(value interface{}) bool { return !equal(value, c.Value) }
+	case "gt":
+		match = compare(func(a, b float64) bool { return a > b })
+	case "gte":
+		match = compare(func(a, b float64) bool { return a >= b })
+	case "lt":
+		match = compare(func(a, b float64) bool { return a < b })
+	case "lte":
+		match = compare(func(a, b float64) bool { return a <= b })
+	case "in":
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("op in requires an array value")
+		}
+		match = func(value interface{}) bool { return contains(values, value) }
+	case "exists", "missing":
+	default:
+		return nil, fmt.Errorf("illegal op: %s", c.Op)
+	}
+
+	return func(raw interface{}) bool {
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		value, exists := lookup(object, c.Field)
+		switch c.Op {
+		case "exists":
+			return exists
+		case "missing":
+			return !exists
+		}
+		return exists && match(value)
+	}, nil
+}
+
+// StageConfig 配置文件中的处理阶段，type 为 decode、validate、enrich、rename、project、drop、split
+type StageConfig struct {
+	Type string `json:"type"`
+	// decode: 解码器名称，内置 json
+	Decoder string `json:"decoder"`
+	// validate: 内联 JSON Schema 或 Schema 文件路径，相对路径基于配置文件所在目录
+	Schema     json.RawMessage `json:"schema"`
+	SchemaFile string          `json:"schemaFile"`
+	// enrich: 以 key 字段查询缓存 cache，结果写入 target
+	Key    string `json:"key"`
+	Target string `json:"target"`
+	Cache  string `json:"cache"`
+	// rename: 原字段 -> 新字段
+	Rename map[string]string `json:"rename"`
+	// project: 保留的字段
+	Fields []string `json:"fields"`
+	// drop: 满足任一条件时丢弃
+	When []Condition `json:"when"`
+	// split: 拆分的数组字段
+	Field string `json:"field"`
+}
+
+// Config 数据处理管道配置
+//
+//	{
+//	  "sources": ["kafka"],
+//	  "stages": [
+//	    {"type":"decode","decoder":"kafka"},
+//	    {"type":"validate","schemaFile":"car.schema.json"},
+//	    {"type":"split","field":"items"},
+//	    {"type":"enrich","key":"vin","target":"vehicle","cache":"vehicles"},
+//	    {"type":"rename","rename":{"spd":"speed"}},
+//	    {"type":"drop","when":[{"field":"speed","op":"lt","value":1}]},
+//	    {"type":"project","fields":["vin","speed","vehicle.plate"]}
+//	  ]
+//	}
+type Config struct {
+	Sources []string      `json:"sources"`
+	Stages  []StageConfig `json:"stages"`
+}
+
+// Bindings 配置文件中按名称引用的解码器、缓存及自定义阶段
+type Bindings struct {
+	Decoders map[string]DecodeFunc
+	Caches   map[string]Cache
+	// Stages 自定义阶段，配置中 type 与名称相同时使用
+	Stages map[string]Stage
+}
+
+// LoadFile 从 json 配置文件构造 Pipeline
+func LoadFile(filename string, bindings Bindings) (*Pipeline, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	p, err := config.Build(filepath.Dir(filename), bindings)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return p, nil
+}
+
+// Build 构造 Pipeline，dir 为 schemaFile 相对路径的基准目录
+func (config *Config) Build(dir string, bindings Bindings) (*Pipeline, error) {
+	p := New()
+	if len(config.Sources) > 0 {
+		p.ForSources(config.Sources...)
+	}
+	for i, stageConfig := range config.Stages {
+		stage, err := stageConfig.build(dir, bindings)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %s", i, err.Error())
+		}
+		p.Append(stage)
+	}
+	return p, nil
+}
+
+func (c *StageConfig) build(dir string, bindings Bindings) (Stage, error) {
+	if stage, exists := bindings.Stages[c.Type]; exists {
+		return stage, nil
+	}
+	switch c.Type {
+	case "decode":
+		if c.Decoder == "" || c.Decoder == "json" {
+			return Decode(JSON), nil
+		}
+		decoder, exists := bindings.Decoders[c.Decoder]
+		if !exists {
+			return nil, fmt.Errorf("decoder not found: %s", c.Decoder)
+		}
+		return Decode(decoder), nil
+	case "validate":
+		if c.SchemaFile != "" {
+			filename := c.SchemaFile
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(dir, filename)
+			}
+			schema, err := LoadSchema(filename)
+			if err != nil {
+				return nil, err
+			}
+			return Validate(schema), nil
+		}
+		schema, err := ParseSchema(c.Schema)
+		if err != nil {
+			return nil, err
+		}
+		return Validate(schema), nil
+	case "enrich":
+		cache, exists := bindings.Caches[c.Cache]
+		if !exists {
+			return nil, fmt.Errorf("cache not found: %s", c.Cache)
+		}
+		if c.Key == "" || c.Target == "" {
+			return nil, fmt.Errorf("key and target are required")
+		}
+		return Enrich(c.Key, c.Target, cache), nil
+	case "rename":
+		return Rename(c.Rename), nil
+	case "project":
+		return Project(c.Fields...), nil
+	case "drop":
+		predicates := make([]Predicate, 0, len(c.When))
+		for _, condition := range c.When {
+			predicate, err := condition.Predicate()
+			if err != nil {
+				return nil, err
+			}
+			predicates = append(predicates, predicate)
+		}
+		return Drop(func(value interface{}) bool {
+			for _, predicate := range predicates {
+				if predicate(value) {
+					return true
+				}
+			}
+			return false
+		}), nil
+	case "split":
+		return Split(c.Field), nil
+	}
+	return nil, fmt.Errorf("illegal stage type: %s", c.Type)
+}
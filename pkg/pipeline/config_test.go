@@ -0,0 +1,127 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: config_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 14:50
+ */
+
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+const testConfig = `{
+  "sources": ["kafka"],
+  "stages": [
+    {"type":"decode"},
+    {"type":"validate","schemaFile":"car.schema.json"},
+    {"type":"split","field":"items"},
+    {"type":"enrich","key":"vin","target":"vehicle","cache":"vehicles"},
+    {"type":"rename","rename":{"spd":"speed"}},
+    {"type":"drop","when":[{"field":"speed","op":"lt","value":1}]},
+    {"type":"project","fields":["vin","speed","vehicle.plate"]}
+  ]
+}`
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"type":"object","required":["items"]}`
+	if err := os.WriteFile(filepath.Join(dir, "car.schema.json"), []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "pipeline.json")
+	if err := os.WriteFile(filename, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewMapCache()
+	cache.Set("V1", map[string]interface{}{"plate": "A123", "owner": "alice"})
+	p, err := LoadFile(filename, Bindings{Caches: map[string]Cache{"vehicles": cache}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := `{"items":[{"vin":"V1","spd":60},{"vin":"V2","spd":0},{"vin":"V3","spd":30}]}`
+	results, err := p.Process(context.Background(), pusher.NewData("kafka", raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"vin": "V1", "speed": float64(60), "vehicle": map[string]interface{}{"plate": "A123"}},
+		map[string]interface{}{"vin": "V3", "speed": float64(30)},
+	}
+	got := make([]interface{}, 0, len(results))
+	for _, data := range results {
+		got = append(got, data.Raw())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+
+	// 其他来源的数据原样通过
+	other := pusher.NewData("http", raw)
+	if results, err := p.Process(context.Background(), other); err != nil || len(results) != 1 || results[0] != other {
+		t.Fatalf("other source = %v, %v", results, err)
+	}
+	// 校验失败
+	if _, err := p.Process(context.Background(), pusher.NewData("kafka", `{"vin":"V1"}`)); err == nil {
+		t.Fatal("invalid data passed validation")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage StageConfig
+	}{
+		{name: "unknown type", stage: StageConfig{Type: "zip"}},
+		{name: "unknown decoder", stage: StageConfig{Type: "decode", Decoder: "avro"}},
+		{name: "missing schema file", stage: StageConfig{Type: "validate", SchemaFile: "missing.json"}},
+		{name: "unknown cache", stage: StageConfig{Type: "enrich", Key: "vin", Target: "vehicle", Cache: "none"}},
+		{name: "illegal op", stage: StageConfig{Type: "drop", When: []Condition{{Field: "speed", Op: "like"}}}},
+		{name: "in without array", stage: StageConfig{Type: "drop", When: []Condition{{Field: "speed", Op: "in", Value: 1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Stages: []StageConfig{tt.stage}}
+			if _, err := config.Build(t.TempDir(), Bindings{}); err == nil {
+				t.Fatal("Build succeeded")
+			}
+		})
+	}
+}
+
+func TestConditionPredicate(t *testing.T) {
+	object := map[string]interface{}{"speed": float64(10), "state": "moving", "gps": map[string]interface{}{"lat": 1.5}}
+	tests := []struct {
+		condition Condition
+		match     bool
+	}{
+		{Condition{Field: "speed", Op: "eq", Value: 10}, true},
+		{Condition{Field: "speed", Op: "ne", Value: 10}, false},
+		{Condition{Field: "speed", Op: "gt", Value: 9}, true},
+		{Condition{Field: "speed", Op: "gte", Value: 10}, true},
+		{Condition{Field: "speed", Op: "lt", Value: 10}, false},
+		{Condition{Field: "speed", Op: "lte", Value: 10}, true},
+		{Condition{Field: "state", Op: "in", Value: []interface{}{"idle", "moving"}}, true},
+		{Condition{Field: "gps.lat", Op: "exists"}, true},
+		{Condition{Field: "gps.lng", Op: "missing"}, true},
+		{Condition{Field: "state", Op: "gt", Value: 1}, false},
+	}
+	for _, tt := range tests {
+		predicate, err := tt.condition.Predicate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := predicate(object); match != tt.match {
+			t.Fatalf("%+v = %v, want %v", tt.condition, match, tt.match)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: field
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 10:40
+ */
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookup 读取 a.b 形式的嵌套字段
+func lookup(object map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = object
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// assign 写入嵌套字段，中间层不存在或不是对象时创建
+func assign(object map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := object
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// remove 删除嵌套字段
+func remove(object map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	current := object
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, keys[len(keys)-1])
+}
+
+// cloneValue 深拷贝 map 与 slice，避免修改上游共享的数据
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = cloneValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = cloneValue(item)
+		}
+		return s
+	}
+	return value
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
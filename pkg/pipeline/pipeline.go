@@ -0,0 +1,123 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: pipeline
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 10:00
+ */
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+// Stage 处理阶段，返回的数据进入下一阶段，返回空表示丢弃
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, data pusher.Data) ([]pusher.Data, error)
+}
+
+// StageFunc 将函数转换为 Stage
+type StageFunc func(ctx context.Context, data pusher.Data) ([]pusher.Data, error)
+
+type funcStage struct {
+	name string
+	fn   StageFunc
+}
+
+func (s *funcStage) Name() string {
+	return s.name
+}
+
+func (s *funcStage) Process(ctx context.Context, data pusher.Data) ([]pusher.Data, error) {
+	return s.fn(ctx, data)
+}
+
+// Func 使用函数构造 Stage
+func Func(name string, fn StageFunc) Stage {
+	return &funcStage{name: name, fn: fn}
+}
+
+// Pipeline 按顺序执行各阶段，实现 pusher.Pipeline
+//
+//	hub.SetPipeline(pipeline.New(
+//		pipeline.Decode(pipeline.JSON),
+//		pipeline.Rename(map[string]string{"spd": "speed"}),
+//		pipeline.Drop(func(v interface{}) bool { return v == nil }),
+//	))
+type Pipeline struct {
+	// sources 仅处理来源在其中的数据，为空时处理全部数据
+	sources map[string]struct{}
+	stages  []Stage
+}
+
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// ForSources 仅处理指定来源（Metadata().Source()）的数据，其他数据原样通过
+func (p *Pipeline) ForSources(sources ...string) *Pipeline {
+	p.sources = make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		p.sources[source] = struct{}{}
+	}
+	return p
+}
+
+// Append 追加处理阶段
+func (p *Pipeline) Append(stages ...Stage) *Pipeline {
+	p.stages = append(p.stages, stages...)
+	return p
+}
+
+func (p *Pipeline) Process(ctx context.Context, data pusher.Data) ([]pusher.Data, error) {
+	if len(p.sources) > 0 {
+		if _, ok := p.sources[data.Metadata().Source()]; !ok {
+			return []pusher.Data{data}, nil
+		}
+	}
+	current := []pusher.Data{data}
+	for _, stage := range p.stages {
+		var next []pusher.Data
+		for _, item := range current {
+			results, err := stage.Process(ctx, item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", stage.Name(), err.Error())
+			}
+			next = append(next, results...)
+		}
+		if len(next) == 0 {
+			return nil, nil
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// Chain 组合多个 Pipeline，依次处理
+type Chain []*Pipeline
+
+func (c Chain) Process(ctx context.Context, data pusher.Data) ([]pusher.Data, error) {
+	current := []pusher.Data{data}
+	for _, p := range c {
+		var next []pusher.Data
+		for _, item := range current {
+			results, err := p.Process(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, results...)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+var (
+	_ pusher.Pipeline = (*Pipeline)(nil)
+	_ pusher.Pipeline = Chain(nil)
+)
@@ -0,0 +1,227 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: schema
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 11:00
+ */
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema JSON Schema 的常用子集：type、required、properties、additionalProperties、items、
+// enum、const、minimum、maximum、minLength、maxLength、pattern、minItems、maxItems
+type Schema struct {
+	Type                 schemaType         `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaType type 可以是单个类型或类型数组
+type schemaType []string
+
+func (t *schemaType) UnmarshalJSON(raw []byte) error {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		*t = schemaType{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+// ParseSchema 解析 JSON Schema
+func ParseSchema(raw []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, err
+	}
+	if err := schema.compile(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// LoadSchema 从文件加载 JSON Schema
+func LoadSchema(filename string) (*Schema, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return schema, nil
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("illegal pattern %q", s.Pattern)
+		}
+		s.pattern = pattern
+	}
+	for _, property := range s.Properties {
+		if err := property.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// Validate 校验数据，数据应为 json 解码后的通用结构
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if len(s.Type) > 0 && !s.matchType(value) {
+		return fmt.Errorf("%s: expected %s", path, strings.Join(s.Type, " or "))
+	}
+	if len(s.Enum) > 0 && !contains(s.Enum, value) {
+		return fmt.Errorf("%s: not in enum", path)
+	}
+	if s.Const != nil && !equal(s.Const, value) {
+		return fmt.Errorf("%s: must be %v", path, s.Const)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, exists := v[name]; !exists {
+				return fmt.Errorf("%s: %s is required", path, name)
+			}
+		}
+		for name, item := range v {
+			property, exists := s.Properties[name]
+			if !exists {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: additional property %s", path, name)
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s: at least %d items", path, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("%s: at most %d items", path, *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fmt.Errorf("%s: shorter than %d", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fmt.Errorf("%s: longer than %d", path, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fmt.Errorf("%s: does not match %s", path, s.Pattern)
+		}
+	default:
+		if number, ok := toFloat(v); ok {
+			if s.Minimum != nil && number < *s.Minimum {
+				return fmt.Errorf("%s: less than %v", path, *s.Minimum)
+			}
+			if s.Maximum != nil && number > *s.Maximum {
+				return fmt.Errorf("%s: greater than %v", path, *s.Maximum)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) matchType(value interface{}) bool {
+	for _, t := range s.Type {
+		switch t {
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "number":
+			if _, ok := toFloat(value); ok {
+				return true
+			}
+		case "integer":
+			if number, ok := toFloat(value); ok && number == float64(int64(number)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, item := range values {
+		if equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,95 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: schema_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 14:30
+ */
+
+package pipeline
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const carSchema = `{
+  "type": "object",
+  "required": ["vin", "speed"],
+  "additionalProperties": false,
+  "properties": {
+    "vin": {"type": "string", "minLength": 3, "maxLength": 17, "pattern": "^[A-Z0-9]+$"},
+    "speed": {"type": "number", "minimum": 0, "maximum": 300},
+    "gear": {"type": "integer", "enum": [1, 2, 3]},
+    "kind": {"const": "car"},
+    "driver": {"type": ["string", "null"]},
+    "tags": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string"}},
+    "online": {"type": "boolean"}
+  }
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(carSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		data  string
+		valid bool
+	}{
+		{name: "minimal", data: `{"vin":"ABC","speed":0}`, valid: true},
+		{name: "full", data: `{"vin":"ABC123","speed":88.5,"gear":2,"kind":"car","driver":null,"tags":["a","b"],"online":true}`, valid: true},
+		{name: "not object", data: `[1]`, valid: false},
+		{name: "missing required", data: `{"vin":"ABC"}`, valid: false},
+		{name: "additional property", data: `{"vin":"ABC","speed":1,"color":"red"}`, valid: false},
+		{name: "wrong type", data: `{"vin":123,"speed":1}`, valid: false},
+		{name: "below minimum", data: `{"vin":"ABC","speed":-1}`, valid: false},
+		{name: "above maximum", data: `{"vin":"ABC","speed":301}`, valid: false},
+		{name: "too short", data: `{"vin":"AB","speed":1}`, valid: false},
+		{name: "too long", data: `{"vin":"ABCDEFGHIJKLMNOPQR","speed":1}`, valid: false},
+		{name: "pattern", data: `{"vin":"abc","speed":1}`, valid: false},
+		{name: "not integer", data: `{"vin":"ABC","speed":1,"gear":1.5}`, valid: false},
+		{name: "not in enum", data: `{"vin":"ABC","speed":1,"gear":4}`, valid: false},
+		{name: "const", data: `{"vin":"ABC","speed":1,"kind":"truck"}`, valid: false},
+		{name: "type union", data: `{"vin":"ABC","speed":1,"driver":7}`, valid: false},
+		{name: "too few items", data: `{"vin":"ABC","speed":1,"tags":[]}`, valid: false},
+		{name: "too many items", data: `{"vin":"ABC","speed":1,"tags":["a","b","c"]}`, valid: false},
+		{name: "item type", data: `{"vin":"ABC","speed":1,"tags":[1]}`, valid: false},
+		{name: "boolean", data: `{"vin":"ABC","speed":1,"online":"yes"}`, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.data), &value); err != nil {
+				t.Fatal(err)
+			}
+			err := schema.Validate(value)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Validate(%s) = %v, want valid %v", tt.data, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSchemaLengthCountsRunes(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"type":"string","maxLength":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate("京A"); err != nil {
+		t.Fatalf("two runes rejected: %v", err)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"type":1}`,
+		`{"properties":{"vin":{"pattern":"("}}}`,
+		`{"items":{"pattern":"["}}`,
+	} {
+		if _, err := ParseSchema([]byte(raw)); err == nil {
+			t.Fatalf("ParseSchema(%s) succeeded", raw)
+		}
+	}
+}
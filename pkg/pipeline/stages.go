@@ -0,0 +1,206 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: stages
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 10:20
+ */
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+// DecodeFunc 将原始数据解码为 map、slice 等通用结构，供后续阶段按字段处理
+type DecodeFunc func(raw interface{}) (interface{}, error)
+
+// JSON 解码 []byte、string、json.RawMessage，其他类型经 json 编解码转换为通用结构
+func JSON(raw interface{}) (interface{}, error) {
+	var body []byte
+	switch v := raw.(type) {
+	case []byte:
+		body = v
+	case json.RawMessage:
+		body = v
+	case string:
+		body = []byte(v)
+	default:
+		var err error
+		if body, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Decode 解码原始数据
+func Decode(decode DecodeFunc) Stage {
+	return Func("decode", func(_ context.Context, data pusher.Data) ([]pusher.Data, error) {
+		value, err := decode(data.Raw())
+		if err != nil {
+			return nil, err
+		}
+		return []pusher.Data{pusher.WithRaw(data, value)}, nil
+	})
+}
+
+// Validate 按 JSON Schema 校验数据，校验失败时丢弃并返回错误
+func Validate(schema *Schema) Stage {
+	return Func("validate", func(_ context.Context, data pusher.Data) ([]pusher.Data, error) {
+		if err := schema.Validate(data.Raw()); err != nil {
+			return nil, err
+		}
+		return []pusher.Data{data}, nil
+	})
+}
+
+// Cache 补全数据使用的缓存
+type Cache interface {
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+}
+
+// MapCache 内存缓存
+type MapCache struct {
+	mutex sync.RWMutex
+	items map[string]interface{}
+}
+
+func NewMapCache() *MapCache {
+	return &MapCache{items: make(map[string]interface{})}
+}
+
+func (c *MapCache) Set(key string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items[key] = value
+}
+
+func (c *MapCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.items, key)
+}
+
+func (c *MapCache) Get(_ context.Context, key string) (interface{}, bool, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	value, exists := c.items[key]
+	return value, exists, nil
+}
+
+// Enrich 以 key 字段的值查询缓存，结果写入 target 字段，缓存未命中时数据原样通过
+func Enrich(key, target string, cache Cache) Stage {
+	return Func("enrich", func(ctx context.Context, data pusher.Data) ([]pusher.Data, error) {
+		object, err := objectOf(data)
+		if err != nil {
+			return nil, err
+		}
+		value, exists := lookup(object, key)
+		if !exists || value == nil {
+			return []pusher.Data{data}, nil
+		}
+		found, hit, err := cache.Get(ctx, toString(value))
+		if err != nil {
+			return nil, err
+		}
+		if !hit {
+			return []pusher.Data{data}, nil
+		}
+		object = cloneValue(object).(map[string]interface{})
+		assign(object, target, cloneValue(found))
+		return []pusher.Data{pusher.WithRaw(data, object)}, nil
+	})
+}
+
+// Rename 重命名字段，key 为原字段，value 为新字段，支持 a.b 形式的嵌套字段
+func Rename(fields map[string]string) Stage {
+	return Func("rename", func(_ context.Context, data pusher.Data) ([]pusher.Data, error) {
+		object, err := objectOf(data)
+		if err != nil {
+			return nil, err
+		}
+		object = cloneValue(object).(map[string]interface{})
+		for from, to := range fields {
+			if value, exists := lookup(object, from); exists {
+				remove(object, from)
+				assign(object, to, value)
+			}
+		}
+		return []pusher.Data{pusher.WithRaw(data, object)}, nil
+	})
+}
+
+// Project 仅保留指定字段
+func Project(fields ...string) Stage {
+	return Func("project", func(_ context.Context, data pusher.Data) ([]pusher.Data, error) {
+		object, err := objectOf(data)
+		if err != nil {
+			return nil, err
+		}
+		projected := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value, exists := lookup(object, field); exists {
+				assign(projected, field, cloneValue(value))
+			}
+		}
+		return []pusher.Data{pusher.WithRaw(data, projected)}, nil
+	})
+}
+
+// Predicate 判断数据是否满足条件
+type Predicate func(value interface{}) bool
+
+// Drop 丢弃满足条件的数据
+func Drop(predicate Predicate) Stage {
+	return Func("drop", func(_ context.Context, data pusher.Data) ([]pusher.Data, error) {
+		if predicate(data.Raw()) {
+			return nil, nil
+		}
+		return []pusher.Data{data}, nil
+	})
+}
+
+// Split 将 field 字段的数组拆分为多条数据，每个元素作为一条数据，field 为空时拆分数据本身
+func Split(field string) Stage {
+	return Func("split", func(_ context.Context, data pusher.Data) ([]pusher.Data, error) {
+		value := data.Raw()
+		if field != "" {
+			object, err := objectOf(data)
+			if err != nil {
+				return nil, err
+			}
+			value, _ = lookup(object, field)
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %q is not an array", field)
+		}
+		results := make([]pusher.Data, 0, len(items))
+		for i, item := range items {
+			results = append(results, pusher.WithIDRaw(data, data.ID()+"-"+strconv.Itoa(i), item))
+		}
+		return results, nil
+	})
+}
+
+func objectOf(data pusher.Data) (map[string]interface{}, error) {
+	object, ok := data.Raw().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("data %s is not an object, decode it first", data.ID())
+	}
+	return object, nil
+}
@@ -0,0 +1,102 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: auth
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 16:10
+ */
+
+package pusher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Credentials 鉴权结果
+type Credentials struct {
+	// User 用户信息，写入 User.SetUser
+	User interface{}
+	// Claims 用户声明，如角色、租户等，写入 User.SetClaims
+	Claims map[string]interface{}
+	// ExpiresAt 凭证过期时间，过期后断开连接，零值表示不过期
+	ExpiresAt time.Time
+}
+
+// Authenticator 在连接升级前对请求鉴权，返回 *AuthError 时按其状态码拒绝升级
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Credentials, error)
+}
+
+// Refresher 支持客户端通过 refresh 方法在连接上刷新凭证的 Authenticator 需实现该接口
+type Refresher interface {
+	Refresh(ctx context.Context, token string) (*Credentials, error)
+}
+
+// AuthError 鉴权失败，Status 为拒绝升级时返回的 http 状态码
+type AuthError struct {
+	Status  int
+	Message string
+}
+
+func NewAuthError(status int, format string, args ...interface{}) *AuthError {
+	return &AuthError{
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// SetAuthenticator 设置连接鉴权，Authenticator 实现了 Refresher 时开启 refresh 方法
+func (h *Hub) SetAuthenticator(authenticator Authenticator) {
+	h.authenticator = authenticator
+	if _, ok := authenticator.(Refresher); ok {
+		h.registerBuiltin("refresh", TypedMethod(h.refresh))
+	}
+}
+
+// RefreshParams refresh 方法参数
+//
+//	{"id":"1","method":"refresh","params":{"token":"..."}}
+type RefreshParams struct {
+	Token string `json:"token"`
+}
+
+// refresh 刷新连接凭证，刷新后的用户标识必须与原用户一致
+func (h *Hub) refresh(ctx context.Context, client Client, params RefreshParams) (interface{}, error) {
+	refresher, ok := h.authenticator.(Refresher)
+	if !ok {
+		return nil, NewError(CodeMethodNotFound, "refresh is not supported")
+	}
+	if params.Token == "" {
+		return nil, NewError(CodeInvalidParams, "token is empty")
+	}
+	credentials, err := refresher.Refresh(ctx, params.Token)
+	if err != nil {
+		return nil, NewError(CodeUnauthorized, "%s", err.Error())
+	}
+
+	if h.credentialsUserID(credentials) != h.UserID(client) {
+		return nil, NewError(CodeForbidden, "token belongs to another user")
+	}
+	client.User().SetClaims(credentials.Claims)
+	client.User().SetUser(credentials.User)
+	client.SetExpiry(credentials.ExpiresAt)
+	h.reauthorize(client)
+
+	result := map[string]interface{}{}
+	if !credentials.ExpiresAt.IsZero() {
+		result["expiresAt"] = credentials.ExpiresAt.Unix()
+	}
+	return result, nil
+}
+
+// credentialsUserID 提取凭证中的用户标识
+func (h *Hub) credentialsUserID(credentials *Credentials) string {
+	return h.userID(&userInfo{user: credentials.User, claims: credentials.Claims})
+}
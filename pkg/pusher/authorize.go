@@ -0,0 +1,74 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: authorize
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 18:20
+ */
+
+package pusher
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Authorizer 需要订阅鉴权的 Handler 实现该接口，返回错误则拒绝订阅
+type Authorizer interface {
+	Authorize(user User, params json.RawMessage) error
+}
+
+// SubscribePolicy 全局订阅策略，先于 Handler 的 Authorize 执行
+type SubscribePolicy func(user User, topic string, params json.RawMessage) error
+
+// SetSubscribePolicy 设置全局订阅策略
+func (h *Hub) SetSubscribePolicy(policy SubscribePolicy) {
+	h.subscribePolicy = policy
+}
+
+// authorize 依次校验全局订阅策略与 Handler 鉴权，拒绝时返回 CodeForbidden
+func (h *Hub) authorize(user User, handler Handler, params json.RawMessage) error {
+	if h.subscribePolicy != nil {
+		if err := h.subscribePolicy(user, handler.Name(), params); err != nil {
+			return forbidden(err)
+		}
+	}
+	if authorizer, ok := handler.(Authorizer); ok {
+		if err := authorizer.Authorize(user, params); err != nil {
+			return forbidden(err)
+		}
+	}
+	return nil
+}
+
+// reauthorize 凭证刷新后重新校验已订阅主题，不再有权限的主题取消订阅并通知客户端
+func (h *Hub) reauthorize(client Client) {
+	var results []TopicResult
+	for _, sub := range client.Subscriptions() {
+		handler, exists := h.GetTopicHandler(sub.Topic)
+		if !exists {
+			continue
+		}
+		err := h.authorize(client.User(), handler, sub.Params)
+		if err == nil {
+			continue
+		}
+		if client.DeleteTopicHandler(sub.Topic) == nil {
+			h.presence.Leave(sub.Topic, client)
+		}
+		results = append(results, NewTopicResult(sub.Topic, err))
+	}
+	if len(results) > 0 {
+		resp := NewResponse("revoke", results)
+		resp.SetCode(CodeForbidden)
+		client.SendMessage(resp)
+	}
+}
+
+func forbidden(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return NewError(CodeForbidden, "%s", err.Error())
+}
@@ -0,0 +1,57 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: backplane
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 10:40
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// MemoryBackplane 进程内 backplane，多个 Hub 共用同一实例即可模拟集群，用于测试
+type MemoryBackplane struct {
+	mutex    sync.RWMutex
+	handlers []func(envelope *Envelope)
+}
+
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{}
+}
+
+func (b *MemoryBackplane) Publish(_ context.Context, envelope *Envelope) error {
+	// 经过一次编解码，避免各节点共享同一份数据
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	b.mutex.RLock()
+	handlers := b.handlers
+	b.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		var copied Envelope
+		if err := json.Unmarshal(raw, &copied); err != nil {
+			return err
+		}
+		handler(&copied)
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(handler func(envelope *Envelope)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *MemoryBackplane) Close() error {
+	return nil
+}
@@ -0,0 +1,154 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: batch
+ * @Version: 1.0.0
+ * @Date: 2026/10/22 17:00
+ */
+
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchSize    = 50
+	defaultBatchLatency = 20 * time.Millisecond
+)
+
+// Batching 批量推送设置，客户端通过 batch 方法开启后，同一次推送的多条消息合并为一个数组帧
+type Batching struct {
+	// MaxSize 单帧最多包含的消息数，默认 50
+	MaxSize int
+	// Latency 即时消息等待合并的最长时间，默认 20ms
+	Latency time.Duration
+}
+
+// SetBatching 允许客户端开启批量推送，未设置时 batch 方法返回错误
+func (h *Hub) SetBatching(batching Batching) {
+	if batching.MaxSize <= 0 {
+		batching.MaxSize = defaultBatchSize
+	}
+	if batching.Latency <= 0 {
+		batching.Latency = defaultBatchLatency
+	}
+
+	h.outbound.mutex.Lock()
+	defer h.outbound.mutex.Unlock()
+
+	h.outbound.batching = &batching
+}
+
+func (o *outbound) getBatching() *Batching {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	return o.batching
+}
+
+// BatchParams batch 方法参数
+type BatchParams struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (h *Hub) batchMethod(_ context.Context, client Client, params BatchParams) (interface{}, error) {
+	batching := h.outbound.getBatching()
+	if batching == nil {
+		return nil, NewError(CodeInvalidParams, "batching is disabled")
+	}
+	if client.Protocol() != ProtocolV2 {
+		return nil, NewError(CodeInvalidParams, "batching requires protocol v2")
+	}
+	client.SetBatch(params.Enabled)
+	return map[string]interface{}{
+		"enabled": params.Enabled,
+		"maxSize": batching.MaxSize,
+		"latency": batching.Latency.Milliseconds(),
+	}, nil
+}
+
+func (c *client) SetBatch(enabled bool) {
+	c.batch.Store(enabled)
+}
+
+// enqueue 暂存待合并的消息，达到 MaxSize 时立即发送，否则在 Latency 内发送，仅在 writePump 中调用
+func (c *client) enqueue(msg Message) {
+	batching := c.hub.outbound.getBatching()
+	if batching == nil {
+		c.SendMessage(msg)
+		return
+	}
+	c.pending = append(c.pending, msg)
+	if len(c.pending) >= batching.MaxSize {
+		c.flush()
+		return
+	}
+	if c.flushC == nil {
+		c.flushC = time.After(batching.Latency)
+	}
+}
+
+// flush 发送暂存的消息，仅在 writePump 中调用
+func (c *client) flush() {
+	c.flushC = nil
+	if len(c.pending) == 0 {
+		return
+	}
+	messages := c.pending
+	c.pending = nil
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.sendBatch(messages)
+}
+
+// sendBatch 将多条消息合并为一个数组帧，超出最大推送大小时逐条发送，需持有 writeMutex
+func (c *client) sendBatch(messages []Message) {
+	batch := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg = c.delta(msg); msg != nil {
+			batch = append(batch, msg)
+		}
+	}
+	if len(batch) <= 1 {
+		for _, msg := range batch {
+			c.send(msg)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	compress := false
+	buf.WriteByte('[')
+	for _, msg := range batch {
+		data, err := c.marshal(msg)
+		if err != nil {
+			logrus.Errorf("%s Encode Message Error: %s", msg.Name(), err.Error())
+			if data, err = c.marshal(failedMessage(msg, NewError(CodeInternal, "encode failed"))); err != nil {
+				continue
+			}
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+		compress = compress || c.hub.outbound.compress(msg.Name(), buf.Len())
+	}
+	buf.WriteByte(']')
+
+	frame, err := c.codec.Encode(buf.Bytes())
+	maxSize, _ := c.hub.outbound.limit()
+	if err != nil || (maxSize > 0 && len(frame) > maxSize) {
+		for _, msg := range batch {
+			c.send(msg)
+		}
+		return
+	}
+	c.write(msgTypeBatch, compress, [][]byte{frame})
+}
@@ -0,0 +1,578 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: centrifuge
+ * @Version: 1.0.0
+ * @Date: 2026/10/23 14:00
+ */
+
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// centrifugeVersion connect 回复中的服务端版本
+const centrifugeVersion = "pusher"
+
+// Centrifuge 客户端协议错误码
+const (
+	centrifugeInternal           = 100
+	centrifugeUnauthorized       = 101
+	centrifugeUnknownChannel     = 102
+	centrifugePermissionDenied   = 103
+	centrifugeMethodNotFound     = 104
+	centrifugeLimitExceeded      = 106
+	centrifugeBadRequest         = 107
+	centrifugeNotAvailable       = 108
+	centrifugeTooManyRequests    = 111
+	centrifugeUnrecoverablePos   = 112
+	centrifugeDisconnectBadToken = 3500
+)
+
+// 映射为 Hub 方法的命令
+const (
+	commandSubscribe     = "subscribe"
+	commandUnsubscribe   = "unsubscribe"
+	commandPublish       = "publish"
+	commandPresence      = "presence"
+	commandPresenceStats = "presence_stats"
+	commandHistory       = "history"
+	commandRPC           = "rpc"
+	commandRefresh       = "refresh"
+)
+
+type centrifugeChannel struct {
+	Channel string `json:"channel"`
+}
+
+type centrifugeCommand struct {
+	ID      uint32 `json:"id"`
+	Connect *struct {
+		Token string          `json:"token"`
+		Data  json.RawMessage `json:"data"`
+	} `json:"connect"`
+	Subscribe *struct {
+		Channel   string          `json:"channel"`
+		Data      json.RawMessage `json:"data"`
+		JoinLeave bool            `json:"join_leave"`
+	} `json:"subscribe"`
+	Unsubscribe *centrifugeChannel `json:"unsubscribe"`
+	Publish     *struct {
+		Channel string          `json:"channel"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"publish"`
+	Presence      *centrifugeChannel `json:"presence"`
+	PresenceStats *centrifugeChannel `json:"presence_stats"`
+	History       *struct {
+		Channel string `json:"channel"`
+		Limit   int    `json:"limit"`
+		Since   *struct {
+			Offset uint64 `json:"offset"`
+		} `json:"since"`
+	} `json:"history"`
+	RPC *struct {
+		Method string          `json:"method"`
+		Data   json.RawMessage `json:"data"`
+	} `json:"rpc"`
+	Refresh *struct {
+		Token string `json:"token"`
+	} `json:"refresh"`
+}
+
+type centrifugeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// centrifugeFailure 无法作为 publication 推送的失败消息（如首次数据加载失败、消息超出大小），以异步消息推送
+type centrifugeFailure struct {
+	Channel string           `json:"channel,omitempty"`
+	Error   *centrifugeError `json:"error"`
+}
+
+type centrifugePublication struct {
+	Data   json.RawMessage `json:"data"`
+	Offset uint64          `json:"offset,omitempty"`
+}
+
+type centrifugeClientInfo struct {
+	Client   string      `json:"client"`
+	User     string      `json:"user"`
+	ConnInfo interface{} `json:"conn_info,omitempty"`
+}
+
+// clientInfo 以连接 id 标识客户端，未知连接 id 时以用户标识代替
+func clientInfo(client string, info PresenceInfo) centrifugeClientInfo {
+	if client == "" {
+		client = info.UserID
+	}
+	return centrifugeClientInfo{Client: client, User: info.UserID, ConnInfo: info.User}
+}
+
+// centrifugePending 等待回复的命令，channel 为本次 subscribe 新增的频道，订阅失败时移除
+type centrifugePending struct {
+	kind    string
+	channel string
+}
+
+// centrifugeReply 已编码的 Centrifuge 回复，不再经过转换
+type centrifugeReply []byte
+
+func (reply centrifugeReply) Name() string {
+	return "centrifuge"
+}
+
+func (reply centrifugeReply) First() bool {
+	return true
+}
+
+func (reply centrifugeReply) Marshal() ([]byte, error) {
+	return reply, nil
+}
+
+// centrifuge Centrifuge JSON 客户端协议适配，将命令转换为 Hub 的请求，将消息转换为回复及推送
+type centrifuge struct {
+	hub    *Hub
+	client string
+	header http.Header
+	url    *url.URL
+
+	mutex     sync.Mutex
+	connected bool
+	// pending 请求 id 对应的命令
+	pending map[string]centrifugePending
+	// channels 小写主题名对应客户端订阅时使用的频道名
+	channels map[string]string
+}
+
+// UpgradeCentrifuge 以 Centrifuge JSON 客户端协议升级连接，可直接使用 centrifuge-js 等客户端 SDK。
+// 鉴权在 connect 命令中进行，令牌通过 Refresher 校验，未实现 Refresher 时以 Authorization 头调用 Authenticator；
+// subscribe、unsubscribe、publish、presence、history 及 rpc 命令映射为 Hub 的同名方法，
+// 主题推送转换为 publication，presence 事件转换为 join/leave，
+// 无法推送的数据（如首次数据加载失败、消息超出大小）转换为带 error 的异步消息
+//
+//	engine.GET("/connection/websocket", func(c *gin.Context) {
+//		client, err := hub.UpgradeCentrifuge(c.Writer, c.Request, upgrader)
+//		if err != nil { return }
+//		client.Run()
+//	})
+func (h *Hub) UpgradeCentrifuge(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) (Client, error) {
+	return h.accept(w, r, upgrader, h.upgradeCentrifuge)
+}
+
+func (h *Hub) upgradeCentrifuge(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) (*client, string, error) {
+	conn, err := h.outbound.upgrader(h.guard.upgrader(upgrader)).Upgrade(w, r, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := NewClient(h, conn)
+	client.protocol = ProtocolCentrifuge
+	client.adapter = &centrifuge{
+		hub:      h,
+		client:   client.ID(),
+		header:   r.Header.Clone(),
+		url:      r.URL,
+		pending:  make(map[string]centrifugePending),
+		channels: make(map[string]string),
+	}
+	h.outbound.setup(conn)
+	return client, "", nil
+}
+
+// handle 处理客户端帧，一帧可包含多条以换行分隔的命令
+func (a *centrifuge) handle(c *client, frame []byte) {
+	for _, line := range bytes.Split(frame, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var cmd centrifugeCommand
+		if err := json.Unmarshal(line, &cmd); err != nil {
+			c.Disconnect("bad request")
+			return
+		}
+		a.command(c, &cmd)
+	}
+}
+
+func (a *centrifuge) command(c *client, cmd *centrifugeCommand) {
+	// 空命令为客户端对 ping 的回复
+	if cmd.ID == 0 {
+		return
+	}
+	if cmd.Connect != nil {
+		// 已连接的会话不能再次 connect，令牌更新应使用 refresh
+		if a.isConnected() {
+			a.reply(c, cmd.ID, "", nil, &centrifugeError{Code: centrifugeBadRequest, Message: "already connected"})
+			return
+		}
+		a.connect(c, cmd.ID, cmd.Connect.Token)
+		return
+	}
+	if !a.isConnected() {
+		a.reply(c, cmd.ID, "", nil, &centrifugeError{Code: centrifugeUnauthorized, Message: "unauthorized"})
+		return
+	}
+
+	var kind, channel string
+	request := ClientRequest{ID: strconv.FormatUint(uint64(cmd.ID), 10)}
+	switch {
+	case cmd.Subscribe != nil:
+		kind, request.Method, request.Topics = commandSubscribe, "subscribe", []string{cmd.Subscribe.Channel}
+		request.Params = subscribeParams(cmd.Subscribe.Data, cmd.Subscribe.JoinLeave)
+		// 首次数据可能先于回复到达，先记录频道，订阅失败时在回复中移除
+		if a.track(cmd.Subscribe.Channel) {
+			channel = cmd.Subscribe.Channel
+		}
+	case cmd.Unsubscribe != nil:
+		kind, request.Method, request.Topics = commandUnsubscribe, "unsubscribe", []string{cmd.Unsubscribe.Channel}
+	case cmd.Publish != nil:
+		kind, request.Method = commandPublish, "publish"
+		request.Params, _ = json.Marshal(PublishParams{Topic: cmd.Publish.Channel, Data: cmd.Publish.Data})
+	case cmd.Presence != nil:
+		kind, request.Method = commandPresence, "presence"
+		request.Params, _ = json.Marshal(PresenceParams{Topic: cmd.Presence.Channel})
+	case cmd.PresenceStats != nil:
+		kind, request.Method = commandPresenceStats, "presence"
+		request.Params, _ = json.Marshal(PresenceParams{Topic: cmd.PresenceStats.Channel})
+	case cmd.History != nil:
+		params := HistoryParams{Topic: cmd.History.Channel, Limit: cmd.History.Limit}
+		if cmd.History.Since != nil {
+			params.After = cmd.History.Since.Offset
+		}
+		kind, request.Method = commandHistory, "history"
+		request.Params, _ = json.Marshal(params)
+	case cmd.RPC != nil:
+		kind, request.Method, request.Params = commandRPC, cmd.RPC.Method, cmd.RPC.Data
+	case cmd.Refresh != nil:
+		kind, request.Method = commandRefresh, "refresh"
+		request.Params, _ = json.Marshal(RefreshParams{Token: cmd.Refresh.Token})
+	default:
+		a.reply(c, cmd.ID, "", nil, &centrifugeError{Code: centrifugeMethodNotFound, Message: "method not found"})
+		return
+	}
+
+	raw, err := json.Marshal(request)
+	if err != nil {
+		a.reply(c, cmd.ID, "", nil, &centrifugeError{Code: centrifugeBadRequest, Message: "bad request"})
+		return
+	}
+	a.mutex.Lock()
+	a.pending[request.ID] = centrifugePending{kind: kind, channel: channel}
+	a.mutex.Unlock()
+	c.hub.HandleRequest(raw, c)
+}
+
+// subscribeParams 订阅参数为 subscribe 命令的 data，join_leave 对应 presence
+func subscribeParams(data json.RawMessage, joinLeave bool) json.RawMessage {
+	params := make(map[string]interface{})
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &params); err != nil {
+			return data
+		}
+	}
+	if joinLeave {
+		params["presence"] = true
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	raw, _ := json.Marshal(params)
+	return raw
+}
+
+func (a *centrifuge) isConnected() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.connected
+}
+
+// track 记录订阅的频道，频道此前未订阅时返回 true
+func (a *centrifuge) track(channel string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	name := strings.ToLower(channel)
+	_, exists := a.channels[name]
+	a.channels[name] = channel
+	return !exists
+}
+
+// channel 主题对应的频道名，未通过 subscribe 命令订阅时返回 false
+func (a *centrifuge) channel(topic string) (string, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	channel, exists := a.channels[strings.ToLower(topic)]
+	return channel, exists
+}
+
+// connect 校验令牌并回复 connect 结果，校验失败时断开连接
+func (a *centrifuge) connect(c *client, id uint32, token string) {
+	if a.hub.authenticator != nil {
+		credentials, err := a.authenticate(token)
+		if err == nil {
+			if authErr := a.hub.guard.AttachUser(c, a.hub.credentialsUserID(credentials)); authErr != nil {
+				err = authErr
+			}
+		}
+		if err != nil {
+			logrus.Warnf("%s Centrifuge Connect Error: %s", c.RemoteAddr(), err.Error())
+			a.reply(c, id, "", nil, &centrifugeError{Code: centrifugeUnauthorized, Message: "unauthorized"})
+			message := websocket.FormatCloseMessage(centrifugeDisconnectBadToken, "invalid token")
+			_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+			c.hub.ClientUnRegister(c)
+			return
+		}
+		c.User().SetClaims(credentials.Claims)
+		c.User().SetUser(credentials.User)
+		c.SetExpiry(credentials.ExpiresAt)
+		a.mutex.Lock()
+		a.connected = true
+		a.mutex.Unlock()
+		a.reply(c, id, "connect", a.connectResult(credentials.ExpiresAt), nil)
+		return
+	}
+	a.mutex.Lock()
+	a.connected = true
+	a.mutex.Unlock()
+	a.reply(c, id, "connect", a.connectResult(time.Time{}), nil)
+}
+
+// authenticate 优先以 Refresher 校验令牌，否则以原始请求头及 Authorization 头调用 Authenticator
+func (a *centrifuge) authenticate(token string) (*Credentials, error) {
+	if refresher, ok := a.hub.authenticator.(Refresher); ok && token != "" {
+		return refresher.Refresh(context.Background(), token)
+	}
+	r := &http.Request{Method: http.MethodGet, URL: a.url, Header: a.header.Clone()}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return a.hub.authenticator.Authenticate(r)
+}
+
+// connectResult connect 及 refresh 的回复，凭证有过期时间时客户端在 ttl 秒内刷新
+func (a *centrifuge) connectResult(expiresAt time.Time) map[string]interface{} {
+	result := map[string]interface{}{
+		"client":  a.client,
+		"version": centrifugeVersion,
+	}
+	if !expiresAt.IsZero() {
+		result["expires"] = true
+		result["ttl"] = uint32(time.Until(expiresAt).Seconds())
+	}
+	return result
+}
+
+// reply 直接发送回复
+func (a *centrifuge) reply(c *client, id uint32, kind string, result interface{}, err *centrifugeError) {
+	raw, marshalErr := encodeReply(id, kind, result, err)
+	if marshalErr != nil {
+		logrus.Errorf("Centrifuge Reply %s Error: %s", kind, marshalErr.Error())
+		return
+	}
+	c.SendMessage(raw)
+}
+
+func encodeReply(id uint32, kind string, result interface{}, err *centrifugeError) (centrifugeReply, error) {
+	reply := map[string]interface{}{"id": id}
+	if err != nil {
+		reply["error"] = err
+	} else {
+		reply[kind] = result
+	}
+	return json.Marshal(reply)
+}
+
+func encodePush(channel string, kind string, value interface{}) ([]byte, error) {
+	push := map[string]interface{}{kind: value}
+	if channel != "" {
+		push["channel"] = channel
+	}
+	return json.Marshal(map[string]interface{}{"push": push})
+}
+
+// centrifugeEnvelope v2 消息中转换需要的字段
+type centrifugeEnvelope struct {
+	ID    string          `json:"id"`
+	Code  ErrorCode       `json:"errcode"`
+	Type  string          `json:"type"`
+	Name  string          `json:"name"`
+	Seq   uint64          `json:"seq"`
+	Body  json.RawMessage `json:"body"`
+	Error string          `json:"error"`
+}
+
+// encode 将 v2 消息转换为回复或推送，返回 nil 时不推送
+func (a *centrifuge) encode(message Message, data []byte) ([]byte, error) {
+	if reply, ok := message.(centrifugeReply); ok {
+		return reply, nil
+	}
+	var envelope centrifugeEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	switch envelope.Type {
+	case msgTypeMethod:
+		return a.encodeReply(&envelope)
+	case msgTypeData:
+		channel, subscribed := a.channel(envelope.Name)
+		if envelope.Code != CodeOK {
+			failure := centrifugeFailure{Channel: channel, Error: &centrifugeError{Code: centrifugeCode(envelope.Code), Message: envelope.Error}}
+			return encodePush(channel, "message", map[string]interface{}{"data": failure})
+		}
+		if !subscribed {
+			// 非订阅主题的消息（如 SendToUser）作为异步消息推送
+			return encodePush("", "message", map[string]json.RawMessage{"data": envelope.Body})
+		}
+		return encodePush(channel, "pub", centrifugePublication{Data: envelope.Body, Offset: envelope.Seq})
+	case msgTypePresence:
+		channel, subscribed := a.channel(envelope.Name)
+		if !subscribed {
+			return nil, nil
+		}
+		var event PresenceEvent
+		if err := json.Unmarshal(envelope.Body, &event); err != nil {
+			return nil, err
+		}
+		return encodePush(channel, event.Event, map[string]interface{}{"info": clientInfo(event.Client, event.PresenceInfo)})
+	}
+	return nil, nil
+}
+
+func (a *centrifuge) encodeReply(envelope *centrifugeEnvelope) ([]byte, error) {
+	// 没有 id 的响应（如 session）不是对客户端命令的回复
+	if envelope.ID == "" {
+		return nil, nil
+	}
+	a.mutex.Lock()
+	pending, exists := a.pending[envelope.ID]
+	delete(a.pending, envelope.ID)
+	a.mutex.Unlock()
+	if !exists {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(envelope.ID, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	code, errMsg := envelope.Code, envelope.Error
+	if code == CodePartialFailure {
+		// 单频道订阅的失败原因在主题结果中
+		var results []TopicResult
+		if json.Unmarshal(envelope.Body, &results) == nil && len(results) > 0 {
+			code, errMsg = results[0].Code, results[0].Error
+		}
+	}
+	kind := pending.kind
+	if code != CodeOK {
+		if pending.channel != "" {
+			a.mutex.Lock()
+			delete(a.channels, strings.ToLower(pending.channel))
+			a.mutex.Unlock()
+		}
+		return encodeReply(uint32(id), kind, nil, &centrifugeError{Code: centrifugeCode(code), Message: errMsg})
+	}
+
+	var result interface{} = struct{}{}
+	switch kind {
+	case commandUnsubscribe:
+		var results []TopicResult
+		if json.Unmarshal(envelope.Body, &results) == nil && len(results) > 0 {
+			a.mutex.Lock()
+			delete(a.channels, strings.ToLower(results[0].Topic))
+			a.mutex.Unlock()
+		}
+	case commandPresence, commandPresenceStats:
+		var users []PresenceInfo
+		if err := json.Unmarshal(envelope.Body, &users); err != nil {
+			return nil, err
+		}
+		if kind == commandPresenceStats {
+			clients := 0
+			for _, user := range users {
+				clients += user.Connections
+			}
+			result = map[string]int{"num_clients": clients, "num_users": len(users)}
+			break
+		}
+		presence := make(map[string]centrifugeClientInfo, len(users))
+		for _, user := range users {
+			if len(user.Clients) == 0 {
+				presence[user.UserID] = clientInfo("", user)
+				continue
+			}
+			for _, client := range user.Clients {
+				presence[client] = clientInfo(client, user)
+			}
+		}
+		result = map[string]interface{}{"presence": presence}
+	case commandHistory:
+		var items []struct {
+			Seq  uint64          `json:"seq"`
+			Body json.RawMessage `json:"body"`
+		}
+		if err := json.Unmarshal(envelope.Body, &items); err != nil {
+			return nil, err
+		}
+		publications := make([]centrifugePublication, 0, len(items))
+		var offset uint64
+		for _, item := range items {
+			publications = append(publications, centrifugePublication{Data: item.Body, Offset: item.Seq})
+			offset = item.Seq
+		}
+		result = map[string]interface{}{"publications": publications, "offset": offset, "epoch": ""}
+	case commandRPC:
+		result = map[string]json.RawMessage{"data": envelope.Body}
+	case commandRefresh:
+		var refreshed struct {
+			ExpiresAt int64 `json:"expiresAt"`
+		}
+		_ = json.Unmarshal(envelope.Body, &refreshed)
+		var expiresAt time.Time
+		if refreshed.ExpiresAt > 0 {
+			expiresAt = time.Unix(refreshed.ExpiresAt, 0)
+		}
+		result = a.connectResult(expiresAt)
+	}
+	return encodeReply(uint32(id), kind, result, nil)
+}
+
+// centrifugeCode 错误码转换为 Centrifuge 错误码
+func centrifugeCode(code ErrorCode) int {
+	switch code {
+	case CodeBadRequest, CodeInvalidParams, CodeTopicEmpty:
+		return centrifugeBadRequest
+	case CodeTopicNotFound, CodeTopicNotSubscribed, CodeConnectionNotFound:
+		return centrifugeUnknownChannel
+	case CodeMethodNotFound:
+		return centrifugeMethodNotFound
+	case CodeForbidden:
+		return centrifugePermissionDenied
+	case CodeUnauthorized:
+		return centrifugeUnauthorized
+	case CodeRateLimited:
+		return centrifugeTooManyRequests
+	case CodeTooManyTopics:
+		return centrifugeLimitExceeded
+	case CodeSessionExpired, CodeResyncRequired:
+		return centrifugeUnrecoverablePos
+	case CodeHistoryDisabled:
+		return centrifugeNotAvailable
+	}
+	return centrifugeInternal
+}
@@ -0,0 +1,238 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: centrifuge_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 17:00
+ */
+
+package pusher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCentrifugeConnectTwice(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := hub.UpgradeCentrifuge(w, r, &websocket.Upgrader{})
+		if err != nil {
+			return
+		}
+		client.Run()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name  string
+		id    uint32
+		error int
+	}{
+		{name: "connect", id: 1},
+		{name: "connect again", id: 2, error: centrifugeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := conn.WriteJSON(map[string]interface{}{"id": tt.id, "connect": map[string]string{}}); err != nil {
+				t.Fatal(err)
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			var reply struct {
+				ID      uint32           `json:"id"`
+				Connect json.RawMessage  `json:"connect"`
+				Error   *centrifugeError `json:"error"`
+			}
+			if err := conn.ReadJSON(&reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply.ID != tt.id {
+				t.Fatalf("reply id = %d, want %d", reply.ID, tt.id)
+			}
+			if tt.error == 0 {
+				if reply.Error != nil || reply.Connect == nil {
+					t.Fatalf("connect failed: %+v", reply.Error)
+				}
+				return
+			}
+			if reply.Error == nil || reply.Error.Code != tt.error {
+				t.Fatalf("reply error = %+v, want code %d", reply.Error, tt.error)
+			}
+		})
+	}
+}
+
+func TestCentrifugeEncodeFailure(t *testing.T) {
+	adapter := &centrifuge{pending: make(map[string]centrifugePending), channels: make(map[string]string)}
+	adapter.track("Car")
+	tests := []struct {
+		name    string
+		message Message
+		channel string
+		code    int
+	}{
+		{name: "snapshot failure", message: NewMessage("car", NewError(CodeForbidden, "denied"), true), channel: "Car", code: centrifugePermissionDenied},
+		{name: "message too large", message: NewMessage("car", NewError(CodeMessageTooLarge, "too large"), false), channel: "Car", code: centrifugeInternal},
+		{name: "not subscribed", message: NewMessage("bus", NewError(CodeInternal, "failed"), false), code: centrifugeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.message.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := adapter.encode(tt.message, data)
+			if err != nil || out == nil {
+				t.Fatalf("encode = %s, %v, want a push", out, err)
+			}
+			var push struct {
+				Push struct {
+					Channel string `json:"channel"`
+					Message struct {
+						Data centrifugeFailure `json:"data"`
+					} `json:"message"`
+				} `json:"push"`
+			}
+			if err := json.Unmarshal(out, &push); err != nil {
+				t.Fatal(err)
+			}
+			failure := push.Push.Message.Data
+			if push.Push.Channel != tt.channel || failure.Channel != tt.channel || failure.Error == nil || failure.Error.Code != tt.code {
+				t.Fatalf("push = %s, want channel %q code %d", out, tt.channel, tt.code)
+			}
+		})
+	}
+}
+
+func TestCentrifugeSubscribeReply(t *testing.T) {
+	tests := []struct {
+		name   string
+		result error
+		error  int
+	}{
+		{name: "subscribed"},
+		{name: "forbidden", result: NewError(CodeForbidden, "denied"), error: centrifugePermissionDenied},
+		{name: "not found", result: NewError(CodeTopicNotFound, "missing"), error: centrifugeUnknownChannel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := &centrifuge{pending: make(map[string]centrifugePending), channels: make(map[string]string)}
+			adapter.track("Bus")
+			// 订阅命令先记录频道，回复时确认
+			if !adapter.track("Car") || adapter.track("Bus") {
+				t.Fatal("track reported an existing channel as new")
+			}
+			adapter.pending["1"] = centrifugePending{kind: commandSubscribe, channel: "Car"}
+			request := &ClientRequest{ID: "1"}
+			client := newStubClient("alice", nil)
+			NewHub().replyResults(client, request, "subscribe", []TopicResult{NewTopicResult("car", tt.result)})
+			data, err := client.sent[0].Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 主题结果与响应外层使用相同的 errcode 字段
+			if strings.Contains(string(data), `"topic":"car","code"`) {
+				t.Fatalf("topic result = %s, want errcode", data)
+			}
+			out, err := adapter.encode(client.sent[0], data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var reply struct {
+				Subscribe json.RawMessage  `json:"subscribe"`
+				Error     *centrifugeError `json:"error"`
+			}
+			if err := json.Unmarshal(out, &reply); err != nil {
+				t.Fatal(err)
+			}
+			// 订阅失败的频道不再按 publication 推送
+			if _, tracked := adapter.channel("car"); tracked != (tt.error == 0) {
+				t.Fatalf("channel tracked = %v after reply %s", tracked, out)
+			}
+			if _, tracked := adapter.channel("bus"); !tracked {
+				t.Fatal("existing channel removed")
+			}
+			if tt.error == 0 {
+				if reply.Error != nil || reply.Subscribe == nil {
+					t.Fatalf("reply = %s, want subscribed", out)
+				}
+				return
+			}
+			if reply.Error == nil || reply.Error.Code != tt.error {
+				t.Fatalf("reply = %s, want error %d", out, tt.error)
+			}
+		})
+	}
+}
+
+func TestCentrifugePresenceClients(t *testing.T) {
+	adapter := &centrifuge{pending: make(map[string]centrifugePending), channels: make(map[string]string)}
+	adapter.track("Room")
+	adapter.pending["1"] = centrifugePending{kind: commandPresence}
+	users := []PresenceInfo{
+		{UserID: "alice", Connections: 2, Clients: []string{"c1", "c2"}},
+		{UserID: "bob", Connections: 1},
+	}
+	resp := NewResponse("presence", users)
+	resp.SetID("1")
+	data, err := resp.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := adapter.encode(resp, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply struct {
+		Presence struct {
+			Presence map[string]centrifugeClientInfo `json:"presence"`
+		} `json:"presence"`
+	}
+	if err := json.Unmarshal(out, &reply); err != nil {
+		t.Fatal(err)
+	}
+	// 每个连接一项，以连接 id 为键
+	want := map[string]string{"c1": "alice", "c2": "alice", "bob": "bob"}
+	if len(reply.Presence.Presence) != len(want) {
+		t.Fatalf("presence = %s", out)
+	}
+	for client, user := range want {
+		if info := reply.Presence.Presence[client]; info.Client != client || info.User != user {
+			t.Fatalf("presence[%s] = %+v, want user %s", client, info, user)
+		}
+	}
+
+	event := newPresenceMessage("room", PresenceEvent{Event: PresenceJoin, Topic: "room", Client: "c3", PresenceInfo: PresenceInfo{UserID: "carol"}})
+	data, err = event.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err = adapter.encode(event, data); err != nil {
+		t.Fatal(err)
+	}
+	var push struct {
+		Push struct {
+			Join struct {
+				Info centrifugeClientInfo `json:"info"`
+			} `json:"join"`
+		} `json:"push"`
+	}
+	if err := json.Unmarshal(out, &push); err != nil {
+		t.Fatal(err)
+	}
+	if info := push.Push.Join.Info; info.Client != "c3" || info.User != "carol" {
+		t.Fatalf("join = %s, want client c3", out)
+	}
+}
@@ -10,13 +10,18 @@ package pusher
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/codec"
+	"github.com/flash520/pusher/pkg/utils"
 )
 
 const (
@@ -36,14 +41,35 @@ const (
 type HandleRequest func([]byte, Client)
 
 type Client interface {
+	// ID 连接唯一标识
+	ID() string
+	// Session 会话令牌，断线重连后通过 resume 方法恢复订阅
+	Session() string
 	SetHub(hub *Hub)
 	User() User
 	SetContext(ctx context.Context, cancelFunc context.CancelFunc)
+	Context() context.Context
 	SendMessage(message Message)
 	HandleMessage(topic string, msg Data)
-	AppendTopicHandler(handler Handler)
-	DeleteTopicHandlers([]string)
+	AppendTopicHandler(handler Handler, params json.RawMessage)
+	// ResumeTopicHandler 订阅主题并重放序号大于 after 的消息，历史已不能覆盖时返回 false 且不订阅
+	ResumeTopicHandler(handler Handler, params json.RawMessage, after uint64) bool
+	// Subscriptions 当前已订阅的主题
+	Subscriptions() []Subscription
+	DeleteTopicHandler(topic string) error
+	// Deprecated: 使用 DeleteTopicHandler，该方法逐个主题回复 unsubscribe 响应
+	DeleteTopicHandlers(topics []string)
+	// SetBatch 开启或关闭批量推送
+	SetBatch(enabled bool)
+	// Resync 重新推送增量同步主题的全量状态
+	Resync(topic string) error
 	RemoteAddr() string
+	// Protocol 客户端协商的协议版本
+	Protocol() ProtocolVersion
+	// SetExpiry 设置凭证过期时间，到期后断开连接，零值表示不过期
+	SetExpiry(expiresAt time.Time)
+	// Disconnect 发送关闭帧后断开连接
+	Disconnect(reason string)
 	Close()
 	Run()
 }
@@ -51,33 +77,81 @@ type Client interface {
 func NewClient(hub *Hub, conn *websocket.Conn) *client {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	c := &client{
-		conn:    conn,
-		hub:     hub,
-		topics:  make(map[string]Handler),
-		queue:   make(map[string]Message),
-		msgChan: make(chan Message),
+		id:       utils.RandString(20),
+		session:  utils.RandString(32),
+		conn:     conn,
+		codec:    codec.JSON(),
+		protocol: hub.protocols.getDefault(),
+		hub:      hub,
+		topics:   make(map[string]*subscription),
+		queue:    make(map[string]Message),
+		states:   make(map[string]*topicState),
+		msgChan:  make(chan Message),
 	}
 	c.user = &userInfo{
 		user:  nil,
 		first: false,
 		msg:   c.ReceiveChan(),
+		onChange: func() {
+			c.hub.refreshUser(c)
+		},
 	}
 	c.SetContext(ctx, cancelFunc)
 	c.hub.ClientRegister(c)
 	return c
 }
 
+// Subscription 客户端订阅的主题及订阅参数
+type Subscription struct {
+	Topic  string
+	Params json.RawMessage
+}
+
+type subscription struct {
+	handler Handler
+	params  json.RawMessage
+	// floor 已重放的最大序号，小于等于该序号的实时消息不再推送
+	floor uint64
+	// ctx Handler 副本的 context，取消订阅或连接断开时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+	// ready 首次数据推送完成前到达的实时数据缓存在 pending 中
+	mutex   sync.Mutex
+	ready   bool
+	pending []Data
+}
+
 type client struct {
-	topicMutex sync.RWMutex
-	hub        *Hub
-	ctx        context.Context
-	cancelFunc context.CancelFunc
-	conn       *websocket.Conn
-	topics     map[string]Handler
-	queueMutex sync.RWMutex
-	queue      map[string]Message
-	msgChan    chan Message
-	user       User
+	id          string
+	session     string
+	topicMutex  sync.RWMutex
+	writeMutex  sync.Mutex
+	hub         *Hub
+	ctx         context.Context
+	cancelFunc  context.CancelFunc
+	conn        *websocket.Conn
+	codec       Codec
+	protocol    ProtocolVersion
+	adapter     *centrifuge
+	topics      map[string]*subscription
+	queueMutex  sync.RWMutex
+	queue       map[string]Message
+	states      map[string]*topicState
+	batch       atomic.Bool
+	pending     []Message
+	flushC      <-chan time.Time
+	msgChan     chan Message
+	user        User
+	expiryMutex sync.Mutex
+	expiry      *time.Timer
+}
+
+func (c *client) ID() string {
+	return c.id
+}
+
+func (c *client) Session() string {
+	return c.session
 }
 
 func (c *client) User() User {
@@ -89,6 +163,10 @@ func (c *client) SetContext(ctx context.Context, cancelFunc context.CancelFunc)
 	c.cancelFunc = cancelFunc
 }
 
+func (c *client) Context() context.Context {
+	return c.ctx
+}
+
 func (c *client) SetHub(hub *Hub) {
 	c.hub = hub
 }
@@ -96,6 +174,9 @@ func (c *client) SetHub(hub *Hub) {
 func (c *client) Run() {
 	go c.readPump()
 	go c.writePump()
+	if c.hub.historyStore() != nil {
+		c.SendMessage(NewResponse("session", map[string]string{"session": c.session}))
+	}
 	logrus.Infof("%s Connected", c.conn.RemoteAddr().String())
 }
 
@@ -112,9 +193,23 @@ func (c *client) readPump() {
 			return
 		}
 
+		// 协议适配的连接只接收文本帧
+		if c.adapter != nil {
+			if mt == websocket.TextMessage {
+				c.adapter.handle(c, msg)
+			}
+			continue
+		}
+
 		switch mt {
 		case websocket.TextMessage:
 			c.hub.HandleRequest(msg, c)
+		case websocket.BinaryMessage:
+			// 解码失败时交由 HandleRequest 按格式错误处理
+			if request, err := c.codec.Decode(msg); err == nil {
+				msg = request
+			}
+			c.hub.HandleRequest(msg, c)
 		case websocket.CloseMessage:
 			return
 		}
@@ -134,10 +229,16 @@ func (c *client) writePump() {
 			c.heartbeat()
 		case <-queueTicker.C:
 			c.dispatch()
+		case <-c.flushC:
+			c.flush()
 		case msg := <-c.msgChan:
 			c.queue[msg.Name()] = msg
 			if msg.First() {
-				c.SendMessage(msg)
+				if c.batch.Load() {
+					c.enqueue(msg)
+				} else {
+					c.SendMessage(msg)
+				}
 				c.queue[msg.Name()] = nil
 			}
 		priority:
@@ -154,65 +255,219 @@ func (c *client) writePump() {
 }
 
 func (c *client) dispatch() {
+	batch := c.batch.Load()
 	c.queueMutex.RLock()
 	for key, msg := range c.queue {
 		if c.queue[key] != nil {
-			c.SendMessage(msg)
+			if batch {
+				c.enqueue(msg)
+			} else {
+				c.SendMessage(msg)
+			}
 			c.queue[key] = nil
 		}
 	}
 	c.queueMutex.RUnlock()
+	if batch {
+		c.flush()
+	}
 }
 
-func (c *client) AppendTopicHandler(handler Handler) {
-	c.queueMutex.RLock()
+// AppendTopicHandler 订阅主题并异步推送首次数据，首次数据推送前到达的实时数据缓存后依次推送
+func (c *client) AppendTopicHandler(handler Handler, params json.RawMessage) {
+	sub := &subscription{
+		handler: handler,
+		params:  params,
+	}
+	c.bind(sub)
+	c.topicMutex.Lock()
+	replaced := c.replace(sub)
+	c.topicMutex.Unlock()
+
+	if replaced != nil {
+		// 重复订阅时清除已同步的状态，首次数据作为新的全量推送
+		c.resetState(handler.Name())
+	}
+	c.detach(replaced)
+	c.attach(sub)
+	go c.snapshot(sub)
+}
 
-	c.topics[strings.ToLower(handler.Name())] = handler
-	c.queueMutex.RUnlock()
+func (c *client) ResumeTopicHandler(handler Handler, params json.RawMessage, after uint64) bool {
+	// 持有锁期间读取历史并重放，保证实时消息在重放之后且不重复
+	c.topicMutex.Lock()
+	entries, ok := c.hub.missed(handler.Name(), after)
+	if !ok {
+		c.topicMutex.Unlock()
+		return false
+	}
+	sub := &subscription{
+		handler: handler,
+		params:  params,
+		floor:   after,
+		ready:   true,
+	}
+	c.bind(sub)
+	replaced := c.replace(sub)
+	if replaced != nil {
+		c.resetState(handler.Name())
+	}
+	for _, entry := range entries {
+		c.hub.view(handler, entry.Data, &replayUser{baseUser: c.user, client: c, seq: entry.Seq})
+		sub.floor = entry.Seq
+	}
+	c.topicMutex.Unlock()
 
-	resp := NewResponse("register", fmt.Sprintf("topic %s subscribe success", handler.Name()))
-	c.SendMessage(resp)
-	c.user.SetFirst(true)
-	handler.TopicView(nil, c.user)
-	c.user.SetFirst(false)
+	c.detach(replaced)
+	c.attach(sub)
+	return true
+}
+
+func (c *client) DeleteTopicHandler(topic string) error {
+	c.topicMutex.Lock()
+	name := strings.ToLower(topic)
+	sub, exists := c.topics[name]
+	if !exists {
+		c.topicMutex.Unlock()
+		return NewError(CodeTopicNotSubscribed, "%s topic not found", topic)
+	}
+	delete(c.topics, name)
+	c.queueMutex.Lock()
+	delete(c.queue, sub.handler.Name())
+	c.queueMutex.Unlock()
+	c.topicMutex.Unlock()
+
+	c.resetState(sub.handler.Name())
+	c.detach(sub)
+	return nil
 }
 
+// Deprecated: 使用 DeleteTopicHandler
 func (c *client) DeleteTopicHandlers(topics []string) {
+	for _, topic := range topics {
+		if err := c.DeleteTopicHandler(topic); err != nil {
+			c.SendMessage(NewResponse("unsubscribe", err))
+			continue
+		}
+		c.hub.presence.Leave(topic, c)
+		c.SendMessage(NewResponse("unsubscribe", fmt.Sprintf("topic %s unsubscribe success", topic)))
+	}
+}
+
+func (c *client) Subscriptions() []Subscription {
 	c.topicMutex.RLock()
 	defer c.topicMutex.RUnlock()
 
-	for _, topic := range topics {
-		_, exists := c.topics[strings.ToLower(topic)]
-		if exists {
-			delete(c.topics, strings.ToLower(topic))
-			delete(c.queue, strings.ToLower(topic))
-			resp := NewResponse("unsubscribe", fmt.Sprintf("topic %s unsubscribe success", topic))
-			c.SendMessage(resp)
-		} else {
-			err := fmt.Errorf("%s topic not found", topic)
-			resp := NewResponse("unsubscribe", err)
-			c.SendMessage(resp)
-		}
+	subscriptions := make([]Subscription, 0, len(c.topics))
+	for _, sub := range c.topics {
+		subscriptions = append(subscriptions, Subscription{
+			Topic:  sub.handler.Name(),
+			Params: sub.params,
+		})
 	}
+	return subscriptions
 }
 
 func (c *client) HandleMessage(topic string, msg Data) {
-	handler, exists := c.topics[strings.ToLower(topic)]
+	c.topicMutex.RLock()
+	sub, exists := c.topics[strings.ToLower(topic)]
+	c.topicMutex.RUnlock()
 	if !exists {
 		return
 	}
-	handler.TopicView(msg, c.user)
+	sub.mutex.Lock()
+	if !sub.ready {
+		sub.buffer(msg)
+		sub.mutex.Unlock()
+		return
+	}
+	sub.mutex.Unlock()
+	c.deliver(sub, msg)
+}
+
+func (c *client) deliver(sub *subscription, msg Data) {
+	// 客户端 publish 的数据默认不回显给发布者
+	if route := Route(msg); route.User() == c.user && !route.Echo() {
+		return
+	}
+	if sequenced, ok := msg.(Sequenced); ok {
+		if sequenced.Seq() <= sub.floor {
+			return
+		}
+		c.hub.view(sub.handler, msg, &sequencedUser{baseUser: c.user, seq: sequenced.Seq()})
+		return
+	}
+	c.hub.view(sub.handler, msg, c.user)
 }
 
 func (c *client) SendMessage(message Message) {
-	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
-		logrus.Errorf("Send Message Timeout, Error: %s", err.Error())
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if message = c.delta(message); message == nil {
 		return
 	}
-	if err := c.conn.WriteMessage(websocket.TextMessage, message.Marshal()); err != nil {
-		logrus.Errorf("%s Send Message Error: %s", message.Name(), err.Error())
+	c.send(message)
+}
+
+// send 编码并写入消息，需持有 writeMutex
+func (c *client) send(message Message) {
+	data, err := c.encode(message)
+	if err != nil {
+		logrus.Errorf("%s Encode Message Error: %s", message.Name(), err.Error())
+		if data, err = c.encode(failedMessage(message, NewError(CodeInternal, "encode failed"))); err != nil {
+			return
+		}
+	}
+	// 协议适配不需要推送的消息编码为空
+	if len(data) == 0 {
 		return
 	}
+	c.write(message.Name(), c.hub.outbound.compress(message.Name(), len(data)), c.frames(message, data))
+}
+
+// write 依次写入帧，需持有 writeMutex
+func (c *client) write(name string, compress bool, frames [][]byte) {
+	messageType := websocket.TextMessage
+	if c.codec.Binary() {
+		messageType = websocket.BinaryMessage
+	}
+	c.conn.EnableWriteCompression(compress)
+	for _, frame := range frames {
+		if len(frame) == 0 {
+			continue
+		}
+		if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+			logrus.Errorf("Send Message Timeout, Error: %s", err.Error())
+			return
+		}
+		if err := c.conn.WriteMessage(messageType, frame); err != nil {
+			logrus.Errorf("%s Send Message Error: %s", name, err.Error())
+			return
+		}
+	}
+}
+
+// failedMessage 消息无法推送时以同名错误消息告知客户端
+func failedMessage(message Message, err error) Message {
+	if resp, ok := message.(*Response); ok {
+		failed := NewResponse(resp.Name(), err)
+		failed.SetID(resp.ID)
+		return failed
+	}
+	return NewMessage(message.Name(), err, false)
+}
+
+// encode 按连接协商的编码序列化消息
+func (c *client) encode(message Message) ([]byte, error) {
+	data, err := c.marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	if c.adapter != nil {
+		return c.adapter.encode(message, data)
+	}
+	return c.codec.Encode(data)
 }
 
 func (c *client) ReceiveChan() chan<- Message {
@@ -220,6 +475,9 @@ func (c *client) ReceiveChan() chan<- Message {
 }
 
 func (c *client) heartbeat() {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
 	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 		logrus.Errorf("Send Message Timeout, Error: %s", err.Error())
 		return
@@ -232,6 +490,9 @@ func (c *client) heartbeat() {
 
 func (c *client) Ping(string) error {
 	logrus.Infof("Receive Client Heartbeat Ping: %s", c.conn.RemoteAddr().String())
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
 	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 		logrus.Errorf("Send Message Timeout, Error: %s", err.Error())
 		return err
@@ -254,8 +515,39 @@ func (c *client) RemoteAddr() string {
 	return c.conn.RemoteAddr().String()
 }
 
+func (c *client) SetExpiry(expiresAt time.Time) {
+	c.expiryMutex.Lock()
+	defer c.expiryMutex.Unlock()
+
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+	if expiresAt.IsZero() {
+		return
+	}
+	c.expiry = time.AfterFunc(time.Until(expiresAt), func() {
+		logrus.Warnf("%s Credentials Expired", c.RemoteAddr())
+		c.Disconnect("credentials expired")
+	})
+}
+
+func (c *client) Disconnect(reason string) {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+	_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+	c.hub.ClientUnRegister(c)
+}
+
 func (c *client) Close() {
+	c.SetExpiry(time.Time{})
 	c.cancelFunc()
+	c.topicMutex.Lock()
+	topics := c.topics
+	c.topics = make(map[string]*subscription)
+	c.topicMutex.Unlock()
+	for _, sub := range topics {
+		c.detach(sub)
+	}
 	_ = c.conn.Close()
 	c.user.Close()
 	close(c.msgChan)
@@ -0,0 +1,483 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: cluster
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 09:30
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/utils"
+)
+
+const (
+	// 节点心跳间隔，心跳同时携带本节点的 presence 快照
+	heartbeatPeriod = 3 * time.Second
+	// 超过该时间未收到心跳的节点视为下线
+	memberTimeout = 10 * time.Second
+	// 本地 presence 变更后延迟同步，合并短时间内的多次变更
+	presenceSyncDelay = 200 * time.Millisecond
+	// 发布到 backplane 的超时时间
+	publishTimeout = 5 * time.Second
+	// 待同步到其他节点的数据缓冲
+	outboxSize = 1024
+)
+
+const (
+	envelopeEvent      = "event"
+	envelopeUser       = "user"
+	envelopeConn       = "conn"
+	envelopeDisconnect = "disconnect"
+	envelopeHeartbeat  = "heartbeat"
+	envelopeLeave      = "leave"
+)
+
+// Envelope 节点间通过 backplane 传递的消息
+type Envelope struct {
+	Kind    string          `json:"kind"`
+	Node    string          `json:"node"`
+	Target  string          `json:"target,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Backplane 节点间消息总线，Publish 的消息需投递给所有节点（包括发送者本身，由 Hub 自行过滤）
+type Backplane interface {
+	Publish(ctx context.Context, envelope *Envelope) error
+	Subscribe(handler func(envelope *Envelope)) error
+	Close() error
+}
+
+// EventDecoder 还原其他节点传来的数据，raw 为原始数据的 json 编码，
+// 未设置时 Data.Raw() 为 json.RawMessage
+type EventDecoder func(source string, raw json.RawMessage) (interface{}, error)
+
+type eventPayload struct {
+	ID     string          `json:"id"`
+	Source string          `json:"source"`
+	Topic  string          `json:"topic,omitempty"`
+	Echo   bool            `json:"echo,omitempty"`
+	User   interface{}     `json:"user,omitempty"`
+	Raw    json.RawMessage `json:"raw"`
+}
+
+type messagePayload struct {
+	Name string          `json:"name"`
+	Body json.RawMessage `json:"body"`
+}
+
+// rawMessage 其他节点已编码好的消息
+type rawMessage struct {
+	name string
+	body []byte
+}
+
+func (msg *rawMessage) Name() string {
+	return msg.name
+}
+
+func (msg *rawMessage) First() bool {
+	return true
+}
+
+func (msg *rawMessage) Marshal() ([]byte, error) {
+	return msg.body, nil
+}
+
+// cluster 集群成员及 backplane
+type cluster struct {
+	mutex     sync.RWMutex
+	node      string
+	backplane Backplane
+	decoder   EventDecoder
+	members   map[string]time.Time
+	onChange  []func(members []string)
+	mode      ClusterMode
+	ring      *hashRing
+	sync      *time.Timer
+	outbox    chan Data
+	// dropped 因缓冲已满未同步给其他节点的数据数
+	dropped atomic.Uint64
+	cancel  context.CancelFunc
+	// join 串行化 SetBackplane 与 LeaveCluster
+	join sync.Mutex
+}
+
+func newCluster() *cluster {
+	return &cluster{
+		node:    utils.RandString(12),
+		members: make(map[string]time.Time),
+	}
+}
+
+// Members 当前存活的节点，包含本节点，按节点标识排序
+func (c *cluster) Members() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	members := []string{c.node}
+	for node := range c.members {
+		members = append(members, node)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func (c *cluster) touch(node string) {
+	c.mutex.Lock()
+	_, exists := c.members[node]
+	c.members[node] = time.Now()
+	c.mutex.Unlock()
+
+	if !exists {
+		logrus.Infof("Cluster Member Joined: %s", node)
+		c.changed()
+	}
+}
+
+func (c *cluster) remove(node string) bool {
+	c.mutex.Lock()
+	_, exists := c.members[node]
+	delete(c.members, node)
+	c.mutex.Unlock()
+
+	if exists {
+		logrus.Warnf("Cluster Member Left: %s", node)
+		c.changed()
+	}
+	return exists
+}
+
+// expired 返回超时未心跳的节点
+func (c *cluster) expired() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var nodes []string
+	for node, seen := range c.members {
+		if time.Since(seen) > memberTimeout {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// OnChange 注册成员变更回调
+func (c *cluster) OnChange(callback func(members []string)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onChange = append(c.onChange, callback)
+}
+
+func (c *cluster) changed() {
+	members := c.Members()
+	c.mutex.RLock()
+	callbacks := c.onChange
+	c.mutex.RUnlock()
+	for _, callback := range callbacks {
+		callback(members)
+	}
+}
+
+// SetNodeID 设置本节点标识，需在 SetBackplane 之前调用，默认随机生成
+func (h *Hub) SetNodeID(node string) {
+	h.cluster.mutex.Lock()
+	defer h.cluster.mutex.Unlock()
+
+	h.cluster.node = node
+}
+
+func (h *Hub) NodeID() string {
+	h.cluster.mutex.RLock()
+	defer h.cluster.mutex.RUnlock()
+
+	return h.cluster.node
+}
+
+// Members 集群中存活的节点
+func (h *Hub) Members() []string {
+	return h.cluster.Members()
+}
+
+// SetEventDecoder 设置其他节点数据的还原方式
+func (h *Hub) SetEventDecoder(decoder EventDecoder) {
+	h.cluster.mutex.Lock()
+	defer h.cluster.mutex.Unlock()
+
+	h.cluster.decoder = decoder
+}
+
+// SetBackplane 开启集群模式，任一节点写入的数据、定向推送及 presence 在所有节点间同步；
+// 重复设置同一 backplane 时不做处理，更换 backplane 前需先 LeaveCluster
+func (h *Hub) SetBackplane(backplane Backplane) error {
+	h.cluster.join.Lock()
+	defer h.cluster.join.Unlock()
+
+	if current := h.getBackplane(); current != nil {
+		if current == backplane {
+			return nil
+		}
+		return errors.New("backplane already set, leave the cluster first")
+	}
+	if err := backplane.Subscribe(h.receiveEnvelope); err != nil {
+		return err
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	outbox := make(chan Data, outboxSize)
+	h.cluster.mutex.Lock()
+	h.cluster.backplane = backplane
+	h.cluster.outbox = outbox
+	h.cluster.cancel = cancelFunc
+	h.cluster.mutex.Unlock()
+
+	h.presence.SetOnChange(h.schedulePresenceSync)
+	h.cluster.OnChange(h.rebalance)
+	h.rebalance(h.Members())
+	go h.heartbeat(ctx)
+	go h.forward(ctx, outbox)
+	logrus.Infof("Cluster Mode Enabled, Node: %s", h.NodeID())
+	return nil
+}
+
+// LeaveCluster 通知其他节点本节点下线并关闭 backplane
+func (h *Hub) LeaveCluster() error {
+	h.cluster.join.Lock()
+	defer h.cluster.join.Unlock()
+
+	backplane := h.getBackplane()
+	if backplane == nil {
+		return nil
+	}
+	h.rebalance(nil)
+	h.cluster.mutex.Lock()
+	h.cluster.cancel()
+	h.cluster.backplane = nil
+	h.cluster.outbox = nil
+	h.cluster.mutex.Unlock()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancelFunc()
+	_ = backplane.Publish(ctx, &Envelope{Kind: envelopeLeave, Node: h.NodeID()})
+	return backplane.Close()
+}
+
+func (h *Hub) getBackplane() Backplane {
+	h.cluster.mutex.RLock()
+	defer h.cluster.mutex.RUnlock()
+
+	return h.cluster.backplane
+}
+
+// publishMessage 将消息编码后发布给其他节点
+func (h *Hub) publishMessage(kind string, target string, msg Message) {
+	if h.getBackplane() == nil {
+		return
+	}
+	body, err := msg.Marshal()
+	if err != nil {
+		logrus.Errorf("%s Marshal Message Error: %s", msg.Name(), err.Error())
+		return
+	}
+	h.publishEnvelope(kind, target, messagePayload{Name: msg.Name(), Body: body})
+}
+
+func (h *Hub) publishEnvelope(kind string, target string, payload interface{}) {
+	backplane := h.getBackplane()
+	if backplane == nil {
+		return
+	}
+	envelope := &Envelope{Kind: kind, Node: h.NodeID(), Target: target}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			logrus.Errorf("Backplane Marshal %s Error: %s", kind, err.Error())
+			return
+		}
+		envelope.Payload = raw
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancelFunc()
+	if err := backplane.Publish(ctx, envelope); err != nil {
+		logrus.Errorf("Backplane Publish %s Error: %s", kind, err.Error())
+	}
+}
+
+// forwardEvent 将本节点写入的数据放入待同步缓冲，未开启集群时返回 false。
+// backplane 阻塞导致缓冲已满时丢弃该数据，不影响本节点的推送
+func (h *Hub) forwardEvent(data Data) bool {
+	h.cluster.mutex.RLock()
+	outbox := h.cluster.outbox
+	h.cluster.mutex.RUnlock()
+
+	if outbox == nil {
+		return false
+	}
+	select {
+	case outbox <- data:
+	default:
+		// 持续丢弃时每满一个缓冲记录一次
+		if dropped := h.cluster.dropped.Add(1); dropped%outboxSize == 1 {
+			logrus.Warnf("Backplane Outbox Full, Event %s Dropped, %d Dropped In Total", data.ID(), dropped)
+		}
+	}
+	return true
+}
+
+// DroppedEvents 因 backplane 缓冲已满未同步给其他节点的数据数
+func (h *Hub) DroppedEvents() uint64 {
+	return h.cluster.dropped.Load()
+}
+
+// forward 按写入顺序将数据同步给其他节点
+func (h *Hub) forward(ctx context.Context, outbox <-chan Data) {
+	for {
+		select {
+		case data := <-outbox:
+			h.publishEvent(data)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishEvent 将本节点写入的数据同步给其他节点
+func (h *Hub) publishEvent(data Data) {
+	raw, err := json.Marshal(data.Raw())
+	if err != nil {
+		logrus.Errorf("Backplane Marshal Event %s Error: %s", data.ID(), err.Error())
+		return
+	}
+	route := Route(data)
+	payload := eventPayload{
+		ID:     data.ID(),
+		Source: route.Source(),
+		Topic:  route.Topic(),
+		Echo:   route.Echo(),
+		Raw:    raw,
+	}
+	if user := route.User(); user != nil {
+		payload.User = user.User()
+	}
+	h.publishEnvelope(envelopeEvent, "", payload)
+}
+
+func (h *Hub) receiveEnvelope(envelope *Envelope) {
+	if envelope.Node == h.NodeID() {
+		return
+	}
+	if envelope.Kind != envelopeLeave {
+		h.cluster.touch(envelope.Node)
+	}
+
+	switch envelope.Kind {
+	case envelopeEvent:
+		data, err := h.decodeEvent(envelope.Payload)
+		if err != nil {
+			logrus.Errorf("Backplane Decode Event From %s Error: %s", envelope.Node, err.Error())
+			return
+		}
+		h.Broadcast(data)
+	case envelopeUser, envelopeConn:
+		var payload messagePayload
+		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return
+		}
+		msg := &rawMessage{name: payload.Name, body: payload.Body}
+		if envelope.Kind == envelopeUser {
+			h.sendToUserLocal(envelope.Target, msg)
+		} else {
+			_ = h.sendToConnectionLocal(envelope.Target, msg)
+		}
+	case envelopeDisconnect:
+		var reason string
+		_ = json.Unmarshal(envelope.Payload, &reason)
+		h.disconnectUserLocal(envelope.Target, reason)
+	case envelopeHeartbeat:
+		var snapshot map[string]map[string]PresenceInfo
+		if err := json.Unmarshal(envelope.Payload, &snapshot); err != nil {
+			return
+		}
+		h.presence.ApplyRemote(envelope.Node, snapshot)
+	case envelopeLeave:
+		h.cluster.remove(envelope.Node)
+		h.presence.ApplyRemote(envelope.Node, nil)
+	}
+}
+
+func (h *Hub) decodeEvent(raw json.RawMessage) (Data, error) {
+	var payload eventPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	h.cluster.mutex.RLock()
+	decoder := h.cluster.decoder
+	h.cluster.mutex.RUnlock()
+
+	var value interface{} = payload.Raw
+	if decoder != nil {
+		var err error
+		if value, err = decoder(payload.Source, payload.Raw); err != nil {
+			return nil, err
+		}
+	}
+	meta := &metadata{
+		source: payload.Source,
+		topic:  payload.Topic,
+		echo:   payload.Echo,
+	}
+	if payload.User != nil {
+		meta.SetUser(&userInfo{user: payload.User})
+	}
+	return &data{id: payload.ID, raw: value, metadata: meta}, nil
+}
+
+// heartbeat 定期广播心跳及 presence 快照，并清理超时节点
+func (h *Hub) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatPeriod)
+	defer ticker.Stop()
+	h.syncPresence()
+	for {
+		select {
+		case <-ticker.C:
+			h.syncPresence()
+			for _, node := range h.cluster.expired() {
+				if h.cluster.remove(node) {
+					h.presence.ApplyRemote(node, nil)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Hub) syncPresence() {
+	h.publishEnvelope(envelopeHeartbeat, "", h.presence.Snapshot())
+}
+
+func (h *Hub) schedulePresenceSync() {
+	h.cluster.mutex.Lock()
+	defer h.cluster.mutex.Unlock()
+
+	if h.cluster.sync != nil {
+		return
+	}
+	h.cluster.sync = time.AfterFunc(presenceSyncDelay, func() {
+		h.cluster.mutex.Lock()
+		h.cluster.sync = nil
+		h.cluster.mutex.Unlock()
+		h.syncPresence()
+	})
+}
@@ -0,0 +1,85 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: cluster_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 11:40
+ */
+
+package pusher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForwardEventDoesNotBlock(t *testing.T) {
+	h := &Hub{cluster: newCluster()}
+	h.cluster.outbox = make(chan Data, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			h.forwardEvent(NewData("test", i))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("forwardEvent blocked on full outbox")
+	}
+	if dropped := h.DroppedEvents(); dropped != 2 {
+		t.Fatalf("dropped = %d, want 2", dropped)
+	}
+}
+
+// countBackplane 记录 Subscribe 次数的 Backplane
+type countBackplane struct {
+	mutex      sync.Mutex
+	subscribed int
+}
+
+func (b *countBackplane) Publish(context.Context, *Envelope) error {
+	return nil
+}
+
+func (b *countBackplane) Subscribe(func(envelope *Envelope)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscribed++
+	return nil
+}
+
+func (b *countBackplane) Close() error {
+	return nil
+}
+
+func TestSetBackplaneTwice(t *testing.T) {
+	h := NewHub()
+	backplane := &countBackplane{}
+	for i := 0; i < 2; i++ {
+		if err := h.SetBackplane(backplane); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backplane.subscribed != 1 {
+		t.Fatalf("subscribed %d times, want 1", backplane.subscribed)
+	}
+	if err := h.SetBackplane(&countBackplane{}); err == nil {
+		t.Fatal("replaced backplane without leaving the cluster")
+	}
+	if err := h.SendToConnection("missing", NewResponse("test", nil)); !errors.Is(err, ErrForwarded) {
+		t.Fatalf("send error = %v, want ErrForwarded", err)
+	}
+	if err := h.LeaveCluster(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SendToConnection("missing", NewResponse("test", nil)); errorCode(err) != CodeConnectionNotFound {
+		t.Fatalf("send error = %v, want CodeConnectionNotFound", err)
+	}
+}
@@ -0,0 +1,54 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: codec
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 20:00
+ */
+
+package pusher
+
+import (
+	"sync"
+
+	"github.com/flash520/pusher/pkg/codec"
+)
+
+// Codec 消息编码，客户端通过 Sec-WebSocket-Protocol 子协议协商，未协商时使用 json
+type Codec = codec.Codec
+
+type codecs struct {
+	mutex  sync.RWMutex
+	codecs map[string]Codec
+}
+
+func newCodecs() *codecs {
+	c := &codecs{codecs: make(map[string]Codec)}
+	c.codecs[codec.NameJSON] = codec.JSON()
+	for _, item := range []Codec{codec.MsgPack(), codec.CBOR(), codec.Protobuf()} {
+		c.codecs[item.Name()] = item
+		for _, compressed := range []Codec{codec.Gzip(item, 0), codec.Zstd(item, 0)} {
+			c.codecs[compressed.Name()] = compressed
+		}
+	}
+	return c
+}
+
+func (c *codecs) get(name string) (Codec, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	item, exists := c.codecs[name]
+	return item, exists
+}
+
+// RegisterCodec 注册消息编码，同名编码会被替换。默认已注册 json、msgpack、cbor、protobuf
+// 及二进制编码的 gzip/zstd 压缩版本（如 msgpack+zstd），可注册不同压缩阈值的版本替换默认值
+//
+//	hub.RegisterCodec(codec.Zstd(codec.MsgPack(), 4096))
+func (h *Hub) RegisterCodec(codec Codec) {
+	h.codecs.mutex.Lock()
+	defer h.codecs.mutex.Unlock()
+
+	h.codecs.codecs[codec.Name()] = codec
+}
@@ -9,6 +9,10 @@
 package pusher
 
 const (
-	msgTypeData   string = "data"
-	msgTypeMethod string = "method"
+	msgTypeData     string = "data"
+	msgTypeMethod   string = "method"
+	msgTypePresence string = "presence"
+	msgTypeChunk    string = "chunk"
+	msgTypeDelta    string = "delta"
+	msgTypeBatch    string = "batch"
 )
@@ -29,6 +29,20 @@ type Metadata interface {
 	SetSource(string)
 }
 
+// RouteMetadata 数据的目标主题及发布者，NewData 创建的数据均实现，自定义的 Metadata 可选实现
+type RouteMetadata interface {
+	Metadata
+	// Topic 数据的目标主题，为空时广播给所有主题
+	Topic() string
+	SetTopic(string)
+	// User 发布者，仅客户端 publish 的数据携带
+	User() User
+	SetUser(User)
+	// Echo 是否回显给发布者本人
+	Echo() bool
+	SetEcho(bool)
+}
+
 func NewData(source string, msg interface{}) *data {
 	return &data{
 		id:       utils.RandString(20),
@@ -37,6 +51,43 @@ func NewData(source string, msg interface{}) *data {
 	}
 }
 
+// NewDataWithID 使用指定的 id 构造数据，用于还原持久化或其他节点传来的数据
+func NewDataWithID(id, source string, msg interface{}) *data {
+	return &data{
+		id:       id,
+		raw:      msg,
+		metadata: &metadata{source: source},
+	}
+}
+
+// Route 数据的路由元数据，Metadata 未实现 RouteMetadata 时返回仅包含来源的副本，修改不会回写到数据
+func Route(d Data) RouteMetadata {
+	if route, ok := d.Metadata().(RouteMetadata); ok {
+		return route
+	}
+	return &metadata{source: d.Metadata().Source()}
+}
+
+// WithRaw 复制数据的 id 及元数据并替换原始数据，用于数据处理管道中转换数据
+func WithRaw(d Data, raw interface{}) Data {
+	return WithIDRaw(d, d.ID(), raw)
+}
+
+// WithIDRaw 复制数据的元数据，使用新的 id 及原始数据，用于拆分数据
+func WithIDRaw(d Data, id string, raw interface{}) Data {
+	src := Route(d)
+	return &data{
+		id:  id,
+		raw: raw,
+		metadata: &metadata{
+			source: src.Source(),
+			topic:  src.Topic(),
+			user:   src.User(),
+			echo:   src.Echo(),
+		},
+	}
+}
+
 func (d *data) ID() string {
 	return d.id
 }
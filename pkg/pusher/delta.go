@@ -0,0 +1,221 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: delta
+ * @Version: 1.0.0
+ * @Date: 2026/10/22 15:00
+ */
+
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/flash520/pusher/pkg/patch"
+)
+
+// DeltaMode 增量同步的补丁格式
+type DeltaMode string
+
+const (
+	// DeltaJSONPatch RFC 6902 JSON Patch
+	DeltaJSONPatch DeltaMode = "json-patch"
+	// DeltaMergePatch RFC 7396 JSON Merge Patch，值为 null 的字段无法与删除区分，数据中含有 null 字段时推送全量
+	DeltaMergePatch DeltaMode = "merge-patch"
+)
+
+type deltas struct {
+	mutex sync.RWMutex
+	modes map[string]DeltaMode
+}
+
+func newDeltas() *deltas {
+	return &deltas{modes: make(map[string]DeltaMode)}
+}
+
+func (d *deltas) mode(topic string) (DeltaMode, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	mode, exists := d.modes[strings.ToLower(topic)]
+	return mode, exists
+}
+
+// SetDelta 主题开启增量同步，mode 为空时关闭。开启后服务端记录每个订阅者最后推送的状态，
+// 首次推送全量数据，之后推送 type 为 delta 的补丁，version 为本次推送后的状态版本，base 为补丁基于的版本。
+// 客户端发现 base 与本地版本不一致时调用 resync 方法获取全量数据
+//
+//	hub.SetDelta("VehicleList", pusher.DeltaJSONPatch)
+func (h *Hub) SetDelta(topic string, mode DeltaMode) {
+	h.deltas.mutex.Lock()
+	defer h.deltas.mutex.Unlock()
+
+	if mode == "" {
+		delete(h.deltas.modes, strings.ToLower(topic))
+		return
+	}
+	h.deltas.modes[strings.ToLower(topic)] = mode
+}
+
+// topicState 订阅者最后推送的主题状态
+type topicState struct {
+	version uint64
+	value   interface{}
+	synced  bool
+}
+
+// deltaMessage 增量同步的补丁消息
+type deltaMessage struct {
+	Code      int         `json:"code"`
+	Type      string      `json:"type"`
+	Topic     string      `json:"name"`
+	Seq       uint64      `json:"seq,omitempty"`
+	Version   uint64      `json:"version"`
+	Base      uint64      `json:"base"`
+	Patch     DeltaMode   `json:"patch"`
+	Body      interface{} `json:"body"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+func (msg *deltaMessage) Name() string {
+	return msg.Topic
+}
+
+func (msg *deltaMessage) First() bool {
+	return false
+}
+
+func (msg *deltaMessage) Marshal() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// delta 将增量同步主题的数据消息转换为全量或补丁消息，状态未变化时返回 nil，需持有 writeMutex
+func (c *client) delta(msg Message) Message {
+	data, ok := msg.(*message)
+	if !ok || data.Type != msgTypeData || data.ErrCode != CodeOK || c.protocol != ProtocolV2 {
+		return msg
+	}
+	mode, enabled := c.hub.deltas.mode(data.Topic)
+	if !enabled {
+		return msg
+	}
+	body, err := json.Marshal(data.Body)
+	if err != nil {
+		return msg
+	}
+	value, err := decodeState(body)
+	if err != nil {
+		return msg
+	}
+
+	name := strings.ToLower(data.Topic)
+	state, exists := c.states[name]
+	if !exists {
+		state = &topicState{}
+		c.states[name] = state
+	}
+	if state.synced && reflect.DeepEqual(state.value, value) {
+		return nil
+	}
+	var delta *deltaMessage
+	// Merge Patch 以 null 表示删除，数据中含有 null 字段时推送全量
+	if state.synced && (mode != DeltaMergePatch || patch.Mergeable(value)) {
+		delta = &deltaMessage{
+			Code:      codeSuccess,
+			Type:      msgTypeDelta,
+			Topic:     data.Topic,
+			Seq:       data.Seq,
+			Version:   state.version + 1,
+			Base:      state.version,
+			Patch:     mode,
+			Timestamp: data.Timestamp,
+		}
+		if mode == DeltaMergePatch {
+			delta.Body = patch.Merge(state.value, value)
+		} else {
+			delta.Body = patch.Diff(state.value, value)
+		}
+	}
+	state.version++
+	state.value = value
+	state.synced = true
+
+	// 补丁不小于全量数据时推送全量
+	if delta != nil {
+		if raw, err := json.Marshal(delta.Body); err == nil && len(raw) < len(body) {
+			return delta
+		}
+	}
+	full := *data
+	full.Version = state.version
+	full.Body = json.RawMessage(body)
+	return &full
+}
+
+// decodeState 解码为通用结构，数字保持原样以便比较
+func decodeState(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// Resync 重新推送增量同步主题的全量状态，之后的补丁基于该版本
+func (c *client) Resync(topic string) error {
+	name := strings.ToLower(topic)
+	c.topicMutex.RLock()
+	sub, exists := c.topics[name]
+	c.topicMutex.RUnlock()
+	if !exists {
+		return NewError(CodeTopicNotSubscribed, "%s topic not found", topic)
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	state, exists := c.states[name]
+	if !exists || !state.synced {
+		// 尚未推送过状态，下一条消息即为全量数据
+		return nil
+	}
+	state.version++
+	full := NewMessage(sub.handler.Name(), state.value, false).(*message)
+	full.Version = state.version
+	c.send(full)
+	return nil
+}
+
+// resetState 取消订阅或重复订阅后清除主题状态
+func (c *client) resetState(topic string) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	delete(c.states, strings.ToLower(topic))
+}
+
+// ResyncParams resync 方法参数
+type ResyncParams struct {
+	Topic string `json:"topic"`
+}
+
+func (h *Hub) resync(_ context.Context, client Client, params ResyncParams) (interface{}, error) {
+	if params.Topic == "" {
+		return nil, NewError(CodeTopicEmpty, "topic is empty")
+	}
+	if client.Protocol() != ProtocolV2 {
+		return nil, NewError(CodeInvalidParams, "resync requires protocol v2")
+	}
+	if _, enabled := h.deltas.mode(params.Topic); !enabled {
+		return nil, NewError(CodeInvalidParams, "topic %s is not delta enabled", params.Topic)
+	}
+	if err := client.Resync(params.Topic); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
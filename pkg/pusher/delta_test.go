@@ -0,0 +1,99 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: delta_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 16:20
+ */
+
+package pusher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDeltaMergePatchNull(t *testing.T) {
+	hub := NewHub()
+	hub.SetDelta("DeltaCar", DeltaMergePatch)
+	c := &client{hub: hub, protocol: ProtocolV2, states: make(map[string]*topicState)}
+	long := "a long value that keeps the patch smaller than the full state"
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{name: "first push is full", body: map[string]interface{}{"speed": 1, "note": long}, want: msgTypeData},
+		{name: "changed field is a patch", body: map[string]interface{}{"speed": 2, "note": long}, want: msgTypeDelta},
+		{name: "null field falls back to full", body: map[string]interface{}{"speed": 2, "note": long, "driver": nil}, want: msgTypeData},
+		{name: "patch again without null", body: map[string]interface{}{"speed": 3, "note": long}, want: msgTypeDelta},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			switch msg := c.delta(NewMessage("DeltaCar", tt.body, false)).(type) {
+			case *message:
+				got = msg.Type
+			case *deltaMessage:
+				got = msg.Type
+			}
+			if got != tt.want {
+				t.Fatalf("type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeltaResubscribeSnapshot(t *testing.T) {
+	hub := NewHub()
+	handler := &TypedHandler[map[string]int]{
+		Topic: "DeltaResubscribe",
+		Snapshot: func(context.Context, User) (map[string]int, error) {
+			return map[string]int{"speed": 1}, nil
+		},
+	}
+	hub.TopicRegister(handler)
+	defer hub.TopicUnRegister(handler)
+	hub.SetDelta(handler.Topic, DeltaJSONPatch)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := hub.Upgrade(w, r, &websocket.Upgrader{})
+		if err != nil {
+			return
+		}
+		client.Run()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?protocol=v2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 状态未变化时重复订阅仍应收到全量首次数据
+	for i := 0; i < 2; i++ {
+		if err := conn.WriteJSON(map[string]interface{}{"method": "subscribe", "topics": []string{handler.Topic}}); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			var msg struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("subscribe %d: %v", i+1, err)
+			}
+			if msg.Type == msgTypeData && msg.Name == handler.Topic {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: direct
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 13:40
+ */
+
+package pusher
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// UserIDFunc 从 User 中提取用户唯一标识，返回空字符串表示匿名用户
+type UserIDFunc func(user User) string
+
+// DefaultUserID 默认的用户标识提取方式，支持 string、实现了 ID() string 或 fmt.Stringer 的用户信息
+func DefaultUserID(user User) string {
+	if user == nil {
+		return ""
+	}
+	switch u := user.User().(type) {
+	case string:
+		return u
+	case interface{ ID() string }:
+		return u.ID()
+	case fmt.Stringer:
+		return u.String()
+	}
+	return ""
+}
+
+// directory 连接与用户索引，用于定向推送
+type directory struct {
+	mutex  sync.RWMutex
+	conns  map[string]Client
+	users  map[string]map[string]Client
+	owners map[string]string
+}
+
+func newDirectory() *directory {
+	return &directory{
+		conns:  make(map[string]Client),
+		users:  make(map[string]map[string]Client),
+		owners: make(map[string]string),
+	}
+}
+
+func (d *directory) Add(client Client) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.conns[client.ID()] = client
+}
+
+// Bind 绑定连接所属用户，用户变更时先解除旧的绑定
+func (d *directory) Bind(client Client, userID string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, exists := d.conns[client.ID()]; !exists {
+		return
+	}
+	d.unbind(client.ID())
+	if userID == "" {
+		return
+	}
+	conns, exists := d.users[userID]
+	if !exists {
+		conns = make(map[string]Client)
+		d.users[userID] = conns
+	}
+	conns[client.ID()] = client
+	d.owners[client.ID()] = userID
+}
+
+func (d *directory) Remove(client Client) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.unbind(client.ID())
+	delete(d.conns, client.ID())
+}
+
+func (d *directory) unbind(connID string) {
+	userID, exists := d.owners[connID]
+	if !exists {
+		return
+	}
+	delete(d.owners, connID)
+	delete(d.users[userID], connID)
+	if len(d.users[userID]) == 0 {
+		delete(d.users, userID)
+	}
+}
+
+func (d *directory) Conn(connID string) (Client, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	client, exists := d.conns[connID]
+	return client, exists
+}
+
+func (d *directory) UserConns(userID string) []Client {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	clients := make([]Client, 0, len(d.users[userID]))
+	for _, client := range d.users[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (d *directory) Owner(connID string) string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.owners[connID]
+}
+
+// SetUserIDFunc 设置用户标识提取方式，默认为 DefaultUserID
+func (h *Hub) SetUserIDFunc(fn UserIDFunc) {
+	h.userID = fn
+}
+
+// UserID 返回连接所属用户标识
+func (h *Hub) UserID(client Client) string {
+	return h.directory.Owner(client.ID())
+}
+
+// refreshUser 用户信息变更后重建用户索引
+func (h *Hub) refreshUser(client Client) {
+	h.directory.Bind(client, h.userID(client.User()))
+}
+
+// SendToUser 推送消息给用户的所有连接，集群模式下同时推送给其他节点上的连接，返回本节点送达的连接数
+func (h *Hub) SendToUser(userID string, msg Message) int {
+	h.publishMessage(envelopeUser, userID, msg)
+	return h.sendToUserLocal(userID, msg)
+}
+
+func (h *Hub) sendToUserLocal(userID string, msg Message) int {
+	clients := h.directory.UserConns(userID)
+	for _, client := range clients {
+		client.SendMessage(msg)
+	}
+	return len(clients)
+}
+
+// ErrForwarded 本节点不存在该连接，消息已转发给其他节点，是否送达未知
+var ErrForwarded = errors.New("connection not on this node, forwarded to cluster")
+
+// SendToConnection 推送消息给指定连接，本节点不存在该连接时返回 CodeConnectionNotFound；
+// 集群模式下改为转发给其他节点并返回 ErrForwarded，没有节点持有该连接时消息被丢弃
+func (h *Hub) SendToConnection(connID string, msg Message) error {
+	err := h.sendToConnectionLocal(connID, msg)
+	if err != nil && h.getBackplane() != nil {
+		h.publishMessage(envelopeConn, connID, msg)
+		return ErrForwarded
+	}
+	return err
+}
+
+func (h *Hub) sendToConnectionLocal(connID string, msg Message) error {
+	client, exists := h.directory.Conn(connID)
+	if !exists {
+		return NewError(CodeConnectionNotFound, "connection not found: %s", connID)
+	}
+	client.SendMessage(msg)
+	return nil
+}
+
+// DisconnectUser 断开用户的所有连接，reason 随关闭帧发送给客户端，集群模式下同时断开其他节点上的连接，
+// 返回本节点断开的连接数
+func (h *Hub) DisconnectUser(userID string, reason string) int {
+	h.publishEnvelope(envelopeDisconnect, userID, reason)
+	return h.disconnectUserLocal(userID, reason)
+}
+
+func (h *Hub) disconnectUserLocal(userID string, reason string) int {
+	clients := h.directory.UserConns(userID)
+	for _, client := range clients {
+		client.Disconnect(reason)
+	}
+	return len(clients)
+}
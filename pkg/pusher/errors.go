@@ -0,0 +1,104 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: errors
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 09:12
+ */
+
+package pusher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode 协议错误码，所有帧统一以 errcode 字段携带，CodeOK 时省略；
+// code 字段仅出现在消息及响应的外层，保持原有含义，1 表示成功、0 表示失败
+type ErrorCode int
+
+const (
+	codeFailure = 0
+	codeSuccess = 1
+)
+
+const (
+	CodeOK ErrorCode = 0
+
+	// 1xxx 客户端请求错误
+	CodeBadRequest         ErrorCode = 1000 // 请求无法解析
+	CodeInvalidParams      ErrorCode = 1001 // 请求参数不合法
+	CodeTopicEmpty         ErrorCode = 1002 // 未携带主题
+	CodeTopicNotFound      ErrorCode = 1003 // 主题不存在
+	CodeTopicNotSubscribed ErrorCode = 1004 // 未订阅该主题
+	CodeMethodNotFound     ErrorCode = 1005 // 方法不存在
+	CodePartialFailure     ErrorCode = 1006 // 部分或全部主题处理失败，详见 body
+	CodeForbidden          ErrorCode = 1007 // 无权限
+	CodeConnectionNotFound ErrorCode = 1008 // 连接不存在
+	CodeUnauthorized       ErrorCode = 1009 // 凭证无效或已过期
+	CodeRateLimited        ErrorCode = 1010 // 请求过于频繁
+	CodeTooManyTopics      ErrorCode = 1011 // 订阅主题数超出限制
+	CodeSessionExpired     ErrorCode = 1012 // 会话不存在或已过期
+	CodeResyncRequired     ErrorCode = 1013 // 历史消息已不能覆盖，需重新同步
+	CodeHistoryDisabled    ErrorCode = 1014 // 未开启历史存储
+
+	// 5xxx 服务端错误
+	CodeInternal        ErrorCode = 5000
+	CodeTimeout         ErrorCode = 5001 // 处理超时
+	CodeMessageTooLarge ErrorCode = 5002 // 消息超出最大推送大小
+)
+
+// Error 携带错误码的协议错误
+type Error struct {
+	Code    ErrorCode `json:"errcode"`
+	Message string    `json:"message"`
+}
+
+func NewError(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errorCode 提取错误码，非 *Error 类型的错误统一视为服务端错误
+func errorCode(err error) ErrorCode {
+	if err == nil {
+		return CodeOK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// statusCode 错误码对应的 code 字段
+func statusCode(code ErrorCode) int {
+	if code == CodeOK {
+		return codeSuccess
+	}
+	return codeFailure
+}
+
+// TopicResult 多主题请求中单个主题的处理结果
+type TopicResult struct {
+	Topic string    `json:"topic"`
+	Code  ErrorCode `json:"errcode,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+func NewTopicResult(topic string, err error) TopicResult {
+	result := TopicResult{
+		Topic: topic,
+		Code:  errorCode(err),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,365 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: guard
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 19:45
+ */
+
+package pusher
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/utils"
+)
+
+// Limits 连接与请求限制，数值为 0 表示不限制
+type Limits struct {
+	// AllowedOrigins 允许的 Origin，支持 *.example.com 通配子域名，为空时使用 Upgrader 自身的校验
+	AllowedOrigins []string
+	// MaxConnections 最大连接总数
+	MaxConnections int
+	// MaxConnectionsPerIP 单个 IP 最大连接数
+	MaxConnectionsPerIP int
+	// MaxConnectionsPerUser 单个用户最大连接数，仅对鉴权后的用户生效
+	MaxConnectionsPerUser int
+	// MaxTopicsPerClient 单个连接最大订阅主题数
+	MaxTopicsPerClient int
+	// SubscribeRate、SubscribeBurst 单个连接每秒 subscribe 请求数及突发容量
+	SubscribeRate  float64
+	SubscribeBurst int
+	// RequestRate、RequestBurst 单个连接每秒请求帧数及突发容量
+	RequestRate  float64
+	RequestBurst int
+	// MaxMalformed 单个 IP 在 MalformedWindow 内发送无法解析的请求达到该次数后封禁 BanDuration，
+	// MalformedWindow 为 0 时使用 1 分钟
+	MaxMalformed    int
+	MalformedWindow time.Duration
+	BanDuration     time.Duration
+	// ClientIP 提取客户端 IP，默认取 RemoteAddr，部署在代理后可自行读取 X-Forwarded-For
+	ClientIP func(r *http.Request) string
+}
+
+// 无法解析请求的默认计数窗口
+const defaultMalformedWindow = time.Minute
+
+type guardClient struct {
+	ip        string
+	user      string
+	requests  *utils.TokenBucket
+	subscribe *utils.TokenBucket
+}
+
+type malformedCounter struct {
+	count int
+	since time.Time
+}
+
+// guard 连接与请求限制的状态
+type guard struct {
+	mutex     sync.Mutex
+	limits    Limits
+	total     int
+	ips       map[string]int
+	users     map[string]int
+	clients   map[string]*guardClient
+	malformed map[string]*malformedCounter
+	bans      map[string]time.Time
+	// pruned 上次清理过期计数及封禁的时间
+	pruned time.Time
+}
+
+func newGuard() *guard {
+	return &guard{
+		limits:    Limits{ClientIP: remoteIP, MalformedWindow: defaultMalformedWindow},
+		ips:       make(map[string]int),
+		users:     make(map[string]int),
+		clients:   make(map[string]*guardClient),
+		malformed: make(map[string]*malformedCounter),
+		bans:      make(map[string]time.Time),
+	}
+}
+
+// SetLimits 设置连接与请求限制，仅对通过 Hub.Upgrade 建立的连接生效
+func (h *Hub) SetLimits(limits Limits) {
+	if limits.ClientIP == nil {
+		limits.ClientIP = remoteIP
+	}
+	if limits.MalformedWindow <= 0 {
+		limits.MalformedWindow = defaultMalformedWindow
+	}
+	h.guard.mutex.Lock()
+	defer h.guard.mutex.Unlock()
+
+	h.guard.limits = limits
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (g *guard) ClientIP(r *http.Request) string {
+	g.mutex.Lock()
+	clientIP := g.limits.ClientIP
+	g.mutex.Unlock()
+
+	return clientIP(r)
+}
+
+// CheckOrigin 按 AllowedOrigins 校验 Origin，未携带 Origin 的非浏览器请求放行
+func (g *guard) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, allowed := range g.limits.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || strings.EqualFold(allowed, u.Host) {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(strings.ToLower(u.Hostname()), strings.ToLower(allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// Acquire 为新连接占用名额，失败时返回拒绝升级的 http 状态码与原因
+func (g *guard) Acquire(ip string) *AuthError {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.prune()
+	if until, banned := g.bans[ip]; banned {
+		if time.Now().Before(until) {
+			return NewAuthError(http.StatusForbidden, "%s is temporarily banned", ip)
+		}
+		delete(g.bans, ip)
+	}
+	if g.limits.MaxConnections > 0 && g.total >= g.limits.MaxConnections {
+		return NewAuthError(http.StatusServiceUnavailable, "too many connections")
+	}
+	if g.limits.MaxConnectionsPerIP > 0 && g.ips[ip] >= g.limits.MaxConnectionsPerIP {
+		return NewAuthError(http.StatusTooManyRequests, "too many connections from %s", ip)
+	}
+	g.total++
+	g.ips[ip]++
+	return nil
+}
+
+// Release 释放 Acquire 占用的名额
+func (g *guard) Release(ip string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.release(ip)
+}
+
+func (g *guard) release(ip string) {
+	g.total--
+	g.ips[ip]--
+	if g.ips[ip] <= 0 {
+		delete(g.ips, ip)
+	}
+}
+
+// AcquireUser 为用户占用连接名额，检查与占用在同一把锁内完成，userID 为空时不限制
+func (g *guard) AcquireUser(userID string) *AuthError {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.acquireUser(userID)
+}
+
+func (g *guard) acquireUser(userID string) *AuthError {
+	if userID == "" {
+		return nil
+	}
+	if max := g.limits.MaxConnectionsPerUser; max > 0 && g.users[userID] >= max {
+		return NewAuthError(http.StatusTooManyRequests, "too many connections for user %s", userID)
+	}
+	g.users[userID]++
+	return nil
+}
+
+// ReleaseUser 释放 AcquireUser 占用的名额
+func (g *guard) ReleaseUser(userID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.releaseUser(userID)
+}
+
+func (g *guard) releaseUser(userID string) {
+	if userID == "" {
+		return
+	}
+	g.users[userID]--
+	if g.users[userID] <= 0 {
+		delete(g.users, userID)
+	}
+}
+
+// AttachUser 为已建立的连接占用用户名额，用于连接建立后才鉴权的协议（如 Centrifuge）
+func (g *guard) AttachUser(client Client, userID string) *AuthError {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	state, exists := g.clients[client.ID()]
+	if !exists {
+		return nil
+	}
+	if err := g.acquireUser(userID); err != nil {
+		return err
+	}
+	g.releaseUser(state.user)
+	state.user = userID
+	return nil
+}
+
+// Attach 连接建立后记录连接的限流状态，user 为升级前已通过 AcquireUser 占用名额的用户
+func (g *guard) Attach(client Client, ip, user string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	state := &guardClient{ip: ip, user: user}
+	if g.limits.RequestRate > 0 {
+		state.requests = utils.NewTokenBucket(g.limits.RequestRate, maxInt(g.limits.RequestBurst, 1))
+	}
+	if g.limits.SubscribeRate > 0 {
+		state.subscribe = utils.NewTokenBucket(g.limits.SubscribeRate, maxInt(g.limits.SubscribeBurst, 1))
+	}
+	g.clients[client.ID()] = state
+}
+
+// Detach 连接断开后释放名额
+func (g *guard) Detach(client Client) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	state, exists := g.clients[client.ID()]
+	if !exists {
+		return
+	}
+	delete(g.clients, client.ID())
+	g.release(state.ip)
+	g.releaseUser(state.user)
+}
+
+func (g *guard) state(client Client) *guardClient {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.clients[client.ID()]
+}
+
+// AllowRequest 请求帧限流
+func (g *guard) AllowRequest(client Client) bool {
+	state := g.state(client)
+	return state == nil || state.requests == nil || state.requests.Allow()
+}
+
+// AllowSubscribe subscribe 请求限流
+func (g *guard) AllowSubscribe(client Client) bool {
+	state := g.state(client)
+	return state == nil || state.subscribe == nil || state.subscribe.Allow()
+}
+
+// TopicsAvailable 连接还可订阅的主题数，-1 表示不限制
+func (g *guard) TopicsAvailable(client Client) int {
+	g.mutex.Lock()
+	max := g.limits.MaxTopicsPerClient
+	g.mutex.Unlock()
+
+	if max <= 0 {
+		return -1
+	}
+	if available := max - len(client.Subscriptions()); available > 0 {
+		return available
+	}
+	return 0
+}
+
+// Malformed 记录无法解析的请求，达到阈值时封禁客户端 IP 并返回 true
+func (g *guard) Malformed(client Client) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	state, exists := g.clients[client.ID()]
+	if !exists || g.limits.MaxMalformed <= 0 {
+		return false
+	}
+	g.prune()
+	counter, exists := g.malformed[state.ip]
+	if !exists || time.Since(counter.since) > g.limits.MalformedWindow {
+		counter = &malformedCounter{since: time.Now()}
+		g.malformed[state.ip] = counter
+	}
+	counter.count++
+	if counter.count < g.limits.MaxMalformed {
+		return false
+	}
+	delete(g.malformed, state.ip)
+	g.bans[state.ip] = time.Now().Add(g.limits.BanDuration)
+	logrus.Warnf("%s Banned For %s: too many malformed requests", state.ip, g.limits.BanDuration)
+	return true
+}
+
+// prune 每个 MalformedWindow 至多清理一次过期的计数及封禁，
+// 避免来自大量 IP 的扫描使记录无限增长
+func (g *guard) prune() {
+	now := time.Now()
+	if now.Sub(g.pruned) < g.limits.MalformedWindow {
+		return
+	}
+	g.pruned = now
+	for ip, counter := range g.malformed {
+		if now.Sub(counter.since) > g.limits.MalformedWindow {
+			delete(g.malformed, ip)
+		}
+	}
+	for ip, until := range g.bans {
+		if !now.Before(until) {
+			delete(g.bans, ip)
+		}
+	}
+}
+
+// upgrader 按 AllowedOrigins 包装 Upgrader 的 Origin 校验
+func (g *guard) upgrader(upgrader *websocket.Upgrader) *websocket.Upgrader {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if len(g.limits.AllowedOrigins) == 0 {
+		return upgrader
+	}
+	u := *upgrader
+	u.CheckOrigin = g.CheckOrigin
+	return &u
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
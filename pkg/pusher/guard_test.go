@@ -0,0 +1,116 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: guard_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 11:05
+ */
+
+package pusher
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestGuard(limits Limits) *guard {
+	h := &Hub{guard: newGuard()}
+	h.SetLimits(limits)
+	return h.guard
+}
+
+func TestGuardMalformedWithoutBanDuration(t *testing.T) {
+	g := newTestGuard(Limits{MaxMalformed: 3})
+	client := newStubClient("malformed", nil)
+	g.Attach(client, "10.0.0.1", "")
+
+	for i := 1; i < 3; i++ {
+		if g.Malformed(client) {
+			t.Fatalf("banned after %d malformed requests", i)
+		}
+	}
+	if !g.Malformed(client) {
+		t.Fatal("not banned after MaxMalformed requests")
+	}
+}
+
+func TestGuardUserConnsConcurrent(t *testing.T) {
+	const max = 2
+	g := newTestGuard(Limits{MaxConnectionsPerUser: max})
+
+	var wg sync.WaitGroup
+	var acquired int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if g.AcquireUser("alice") == nil {
+				atomic.AddInt32(&acquired, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if acquired != max {
+		t.Fatalf("acquired %d connections, want %d", acquired, max)
+	}
+
+	// 断开的连接释放名额
+	client := newStubClient("alice-1", nil)
+	g.Attach(client, "10.0.0.2", "alice")
+	g.Detach(client)
+	if err := g.AcquireUser("alice"); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	if err := g.AcquireUser("alice"); err == nil {
+		t.Fatal("acquired beyond MaxConnectionsPerUser")
+	}
+	if err := g.AcquireUser(""); err != nil {
+		t.Fatalf("anonymous user limited: %v", err)
+	}
+}
+
+func TestGuardAttachUser(t *testing.T) {
+	g := newTestGuard(Limits{MaxConnectionsPerUser: 1})
+	first, second := newStubClient("c1", nil), newStubClient("c2", nil)
+	g.Attach(first, "10.0.0.3", "")
+	g.Attach(second, "10.0.0.3", "")
+
+	if err := g.AttachUser(first, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AttachUser(second, "bob"); err == nil {
+		t.Fatal("second connection for bob accepted")
+	}
+	g.Detach(first)
+	if err := g.AttachUser(second, "bob"); err != nil {
+		t.Fatalf("attach after detach: %v", err)
+	}
+}
+
+func TestGuardPrune(t *testing.T) {
+	const window = 20 * time.Millisecond
+	g := newTestGuard(Limits{MaxMalformed: 2, MalformedWindow: window, BanDuration: window})
+	// 扫描来源的 IP 各自只出现一次，部分被封禁
+	for i := 0; i < 100; i++ {
+		client := newStubClient(fmt.Sprintf("scan-%d", i), nil)
+		g.Attach(client, fmt.Sprintf("10.1.0.%d", i), "")
+		g.Malformed(client)
+		if i%2 == 0 {
+			g.Malformed(client)
+		}
+		g.Detach(client)
+	}
+	if len(g.malformed) == 0 || len(g.bans) == 0 {
+		t.Fatalf("malformed %d bans %d, want both recorded", len(g.malformed), len(g.bans))
+	}
+	time.Sleep(2 * window)
+	if err := g.Acquire("10.2.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.malformed) != 0 || len(g.bans) != 0 {
+		t.Fatalf("malformed %d bans %d remain after expiry", len(g.malformed), len(g.bans))
+	}
+}
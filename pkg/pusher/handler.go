@@ -10,6 +10,7 @@ package pusher
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 	"sync"
 )
@@ -22,6 +23,36 @@ type Handler interface {
 	Clone() Handler
 }
 
+// Publishable 允许客户端向主题发布数据的 Handler 需实现该接口，
+// 返回值为校验后的数据，将作为 Data.Raw() 进入广播流程，返回错误则拒绝发布
+type Publishable interface {
+	AuthorizePublish(ctx context.Context, user User, payload json.RawMessage) (interface{}, error)
+}
+
+// Snapshotter 提供订阅时的首次加载数据，实现该接口的 Handler 订阅时不再以 User.First() 调用 TopicView。
+// 首次数据推送之前到达的实时数据会被缓存，在首次数据之后按顺序推送；返回 nil 时不推送首次数据
+type Snapshotter interface {
+	LoadSnapshot(ctx context.Context, user User, params json.RawMessage) (interface{}, error)
+}
+
+// SubscribeHook 订阅生效后调用，ctx 为该订阅的 Handler 副本的 context，取消订阅或连接断开时取消，
+// 可用于启动按订阅者运行的 goroutine（如轮询数据库），需在 ctx 取消时退出
+type SubscribeHook interface {
+	OnSubscribe(ctx context.Context, user User, params json.RawMessage)
+}
+
+// UnsubscribeHook 取消订阅或连接断开后调用，此时 ctx 已取消
+type UnsubscribeHook interface {
+	OnUnsubscribe(user User)
+}
+
+// RegisterHook 主题注册到 Hub 后调用 OnRegister，注销前调用 OnUnRegister，
+// 可用于启动与主题同生命周期的后台任务（如定时推送），需在 OnUnRegister 中停止
+type RegisterHook interface {
+	OnRegister()
+	OnUnRegister()
+}
+
 var defaultTopicHandler = &topicHandlers{
 	container: map[string]Handler{},
 }
@@ -42,15 +73,27 @@ func (topic *topicHandlers) GetTopicHandler(name string) (Handler, bool) {
 }
 
 func (topic *topicHandlers) Register(handler Handler) {
-	topic.mutex.RLock()
-	defer topic.mutex.RUnlock()
+	topic.mutex.Lock()
+	defer topic.mutex.Unlock()
 
 	topic.container[strings.ToLower(handler.Name())] = handler
 }
 
 func (topic *topicHandlers) UnRegister(name string) {
+	topic.mutex.Lock()
+	defer topic.mutex.Unlock()
+
+	delete(topic.container, strings.ToLower(name))
+}
+
+// Handlers 返回当前已注册 Handler 的副本，key 为小写主题名
+func (topic *topicHandlers) Handlers() map[string]Handler {
 	topic.mutex.RLock()
 	defer topic.mutex.RUnlock()
 
-	delete(topic.container, strings.ToLower(name))
+	handlers := make(map[string]Handler, len(topic.container))
+	for name, handler := range topic.container {
+		handlers[name] = handler
+	}
+	return handlers
 }
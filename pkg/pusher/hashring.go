@@ -0,0 +1,59 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: hashring
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 14:10
+ */
+
+package pusher
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// 每个节点在哈希环上的虚拟节点数
+const defaultReplicas = 160
+
+// hashRing 一致性哈希环
+type hashRing struct {
+	replicas int
+	hashes   []uint32
+	nodes    map[uint32]string
+}
+
+func newHashRing(members []string, replicas int) *hashRing {
+	ring := &hashRing{
+		replicas: replicas,
+		hashes:   make([]uint32, 0, len(members)*replicas),
+		nodes:    make(map[uint32]string, len(members)*replicas),
+	}
+	for _, member := range members {
+		for i := 0; i < replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(member + "#" + strconv.Itoa(i)))
+			ring.hashes = append(ring.hashes, hash)
+			ring.nodes[hash] = member
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool {
+		return ring.hashes[i] < ring.hashes[j]
+	})
+	return ring
+}
+
+// Owner 返回 key 所属节点，环为空时返回空字符串
+func (ring *hashRing) Owner(key string) string {
+	if len(ring.hashes) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring.hashes), func(i int) bool {
+		return ring.hashes[i] >= hash
+	})
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.nodes[ring.hashes[i]]
+}
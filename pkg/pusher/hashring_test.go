@@ -0,0 +1,66 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: hashring_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 12:00
+ */
+
+package pusher
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashRingEmpty(t *testing.T) {
+	if owner := newHashRing(nil, defaultReplicas).Owner("car"); owner != "" {
+		t.Fatalf("empty ring owner = %q", owner)
+	}
+	if owner := newHashRing([]string{"node-a"}, defaultReplicas).Owner("car"); owner != "node-a" {
+		t.Fatalf("single node owner = %q", owner)
+	}
+}
+
+func TestHashRingDistribution(t *testing.T) {
+	members := []string{"node-a", "node-b", "node-c"}
+	ring := newHashRing(members, defaultReplicas)
+	reversed := newHashRing([]string{"node-c", "node-b", "node-a"}, defaultReplicas)
+
+	const keys = 3000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		key := "topic-" + strconv.Itoa(i)
+		owner := ring.Owner(key)
+		if other := reversed.Owner(key); other != owner {
+			t.Fatalf("%s owner depends on member order: %s != %s", key, owner, other)
+		}
+		counts[owner]++
+	}
+	for _, member := range members {
+		if share := float64(counts[member]) / keys; share < 0.2 || share > 0.46 {
+			t.Fatalf("%s owns %.2f of keys: %v", member, share, counts)
+		}
+	}
+}
+
+func TestHashRingMinimalMovement(t *testing.T) {
+	before := newHashRing([]string{"node-a", "node-b", "node-c"}, defaultReplicas)
+	after := newHashRing([]string{"node-a", "node-b", "node-c", "node-d"}, defaultReplicas)
+
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		key := "topic-" + strconv.Itoa(i)
+		from, to := before.Owner(key), after.Owner(key)
+		if from == to {
+			continue
+		}
+		if to != "node-d" {
+			t.Fatalf("%s moved from %s to %s instead of the new node", key, from, to)
+		}
+		moved++
+	}
+	if moved == 0 || moved > 1200 {
+		t.Fatalf("%d of 3000 keys moved after adding a node", moved)
+	}
+}
@@ -0,0 +1,345 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: history
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 16:00
+ */
+
+package pusher
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sequenced 携带主题序号的数据，序号按主题单调递增
+type Sequenced interface {
+	Seq() uint64
+}
+
+type sequencedData struct {
+	Data
+	seq uint64
+}
+
+func (d *sequencedData) Seq() uint64 {
+	return d.seq
+}
+
+// sequencer 需要写入主题序号的消息
+type sequencer interface {
+	SetSeq(seq uint64)
+}
+
+// baseUser 用于在包装类型中嵌入 User
+type baseUser = User
+
+// sequencedUser 将 TopicView 写出的消息标记为数据的主题序号
+type sequencedUser struct {
+	baseUser
+	seq uint64
+}
+
+func (u *sequencedUser) Write(msg Message) {
+	if s, ok := msg.(sequencer); ok {
+		s.SetSeq(u.seq)
+	}
+	u.baseUser.Write(msg)
+}
+
+// replayUser 重放时使用，消息不经过推送队列直接发送，避免被合并
+type replayUser struct {
+	baseUser
+	client Client
+	seq    uint64
+}
+
+func (u *replayUser) First() bool {
+	return false
+}
+
+func (u *replayUser) Write(msg Message) {
+	if s, ok := msg.(sequencer); ok {
+		s.SetSeq(u.seq)
+	}
+	u.client.SendMessage(msg)
+}
+
+// HistoryEntry 主题历史数据
+type HistoryEntry struct {
+	Seq   uint64
+	Topic string
+	Time  time.Time
+	Data  Data
+}
+
+// HistoryQuery 历史查询条件，各条件同时生效
+type HistoryQuery struct {
+	// After 序号大于 After 的数据
+	After uint64
+	// Since 不早于 Since 的数据
+	Since time.Time
+	// Limit 最多返回最近的 Limit 条，0 表示不限制
+	Limit int
+}
+
+// HistoryStore 主题历史存储，由广播流程写入并分配主题序号
+type HistoryStore interface {
+	// Append 写入数据并返回分配的序号，序号按主题从 1 开始单调递增
+	Append(topic string, data Data) (uint64, error)
+	// Query 按序号升序返回符合条件的数据
+	Query(topic string, query HistoryQuery) ([]HistoryEntry, error)
+	// Bounds 当前保留数据的最小序号及已分配的最大序号，没有保留数据时 first 为 0
+	Bounds(topic string) (first uint64, last uint64, err error)
+	Close() error
+}
+
+// 断开连接后会话的默认保留时间
+const defaultSessionTTL = 2 * time.Minute
+
+// SetHistoryStore 设置主题历史存储，用于断线重连后的消息重放及 history 查询，nil 表示关闭
+func (h *Hub) SetHistoryStore(store HistoryStore) {
+	h.historyMutex.Lock()
+	defer h.historyMutex.Unlock()
+
+	h.history = store
+}
+
+// SetHistory 使用内存历史存储，每个主题保留 size 条、ttl 时间内的数据，size 为 0 时关闭
+func (h *Hub) SetHistory(size int, ttl time.Duration) {
+	if size <= 0 {
+		h.SetHistoryStore(nil)
+		return
+	}
+	h.SetHistoryStore(NewMemoryHistory(size, ttl))
+}
+
+// SetSessionTTL 设置断开连接后会话的保留时间，默认 2 分钟
+func (h *Hub) SetSessionTTL(ttl time.Duration) {
+	h.historyMutex.Lock()
+	defer h.historyMutex.Unlock()
+
+	h.sessionTTL = ttl
+}
+
+func (h *Hub) historyStore() HistoryStore {
+	h.historyMutex.RLock()
+	defer h.historyMutex.RUnlock()
+
+	return h.history
+}
+
+// History 查询主题历史数据
+func (h *Hub) History(topic string, query HistoryQuery) ([]HistoryEntry, error) {
+	store := h.historyStore()
+	if store == nil {
+		return nil, NewError(CodeHistoryDisabled, "history is disabled")
+	}
+	return store.Query(strings.ToLower(topic), query)
+}
+
+// appendHistory 写入历史并返回主题序号，未开启历史时返回 0
+func (h *Hub) appendHistory(topic string, data Data) uint64 {
+	store := h.historyStore()
+	if store == nil {
+		return 0
+	}
+	seq, err := store.Append(strings.ToLower(topic), data)
+	if err != nil {
+		logrus.Errorf("Append History %s Error: %s", topic, err.Error())
+		return 0
+	}
+	return seq
+}
+
+// missed 返回序号大于 after 的历史，历史已不能覆盖时返回 false，需要重新同步
+func (h *Hub) missed(topic string, after uint64) ([]HistoryEntry, bool) {
+	store := h.historyStore()
+	if store == nil {
+		return nil, false
+	}
+	topic = strings.ToLower(topic)
+	first, last, err := store.Bounds(topic)
+	if err != nil {
+		return nil, false
+	}
+	if after == last {
+		return nil, true
+	}
+	if after > last || first == 0 || first > after+1 {
+		return nil, false
+	}
+	entries, err := store.Query(topic, HistoryQuery{After: after})
+	if err != nil {
+		return nil, false
+	}
+	return entries, true
+}
+
+// HistoryParams history 方法参数，since 为毫秒时间戳
+//
+//	{"id":"1","method":"history","params":{"topic":"car","limit":100}}
+type HistoryParams struct {
+	Topic string `json:"topic"`
+	After uint64 `json:"after"`
+	Since int64  `json:"since"`
+	Limit int    `json:"limit"`
+}
+
+// HistoryItem history 方法返回的单条数据
+type HistoryItem struct {
+	Seq       uint64      `json:"seq"`
+	Timestamp int64       `json:"timestamp"`
+	Body      interface{} `json:"body"`
+}
+
+// 单次 history 查询最多返回的条数
+const maxHistoryLimit = 1000
+
+// historyMethod 仅返回客户端已订阅且仍有权限的主题的历史数据
+func (h *Hub) historyMethod(_ context.Context, client Client, params HistoryParams) (interface{}, error) {
+	if params.Topic == "" {
+		return nil, NewError(CodeTopicEmpty, "topic is empty")
+	}
+	handler, exists := h.GetTopicHandler(params.Topic)
+	if !exists {
+		return nil, NewError(CodeTopicNotFound, "topic not found: %s", params.Topic)
+	}
+	var sub *Subscription
+	for _, item := range client.Subscriptions() {
+		if strings.EqualFold(item.Topic, handler.Name()) {
+			sub = &item
+			break
+		}
+	}
+	if sub == nil {
+		return nil, NewError(CodeTopicNotSubscribed, "%s topic not subscribed", params.Topic)
+	}
+	if err := h.authorize(client.User(), handler, sub.Params); err != nil {
+		return nil, err
+	}
+	if params.Limit <= 0 || params.Limit > maxHistoryLimit {
+		params.Limit = maxHistoryLimit
+	}
+	query := HistoryQuery{After: params.After, Limit: params.Limit}
+	if params.Since > 0 {
+		query.Since = time.UnixMilli(params.Since)
+	}
+	entries, err := h.History(handler.Name(), query)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]HistoryItem, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, HistoryItem{
+			Seq:       entry.Seq,
+			Timestamp: entry.Time.UnixMilli(),
+			Body:      entry.Data.Raw(),
+		})
+	}
+	return items, nil
+}
+
+type topicBuffer struct {
+	seq     uint64
+	entries []HistoryEntry
+}
+
+// MemoryHistory 内存历史存储，每个主题保留最近 size 条、ttl 时间内的数据，ttl 为 0 表示不按时间淘汰
+type MemoryHistory struct {
+	mutex  sync.Mutex
+	size   int
+	ttl    time.Duration
+	topics map[string]*topicBuffer
+}
+
+func NewMemoryHistory(size int, ttl time.Duration) *MemoryHistory {
+	return &MemoryHistory{
+		size:   size,
+		ttl:    ttl,
+		topics: make(map[string]*topicBuffer),
+	}
+}
+
+func (m *MemoryHistory) Append(topic string, data Data) (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	buffer, exists := m.topics[topic]
+	if !exists {
+		buffer = &topicBuffer{}
+		m.topics[topic] = buffer
+	}
+	buffer.seq++
+	buffer.entries = append(buffer.entries, HistoryEntry{
+		Seq:   buffer.seq,
+		Topic: topic,
+		Time:  time.Now(),
+		Data:  data,
+	})
+	m.trim(buffer)
+	return buffer.seq, nil
+}
+
+func (m *MemoryHistory) trim(buffer *topicBuffer) {
+	drop := 0
+	if len(buffer.entries) > m.size {
+		drop = len(buffer.entries) - m.size
+	}
+	if m.ttl > 0 {
+		deadline := time.Now().Add(-m.ttl)
+		for drop < len(buffer.entries) && buffer.entries[drop].Time.Before(deadline) {
+			drop++
+		}
+	}
+	if drop > 0 {
+		buffer.entries = append(buffer.entries[:0:0], buffer.entries[drop:]...)
+	}
+}
+
+func (m *MemoryHistory) Query(topic string, query HistoryQuery) ([]HistoryEntry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	buffer, exists := m.topics[topic]
+	if !exists {
+		return nil, nil
+	}
+	m.trim(buffer)
+	entries := make([]HistoryEntry, 0, len(buffer.entries))
+	for _, entry := range buffer.entries {
+		if entry.Seq > query.After && !entry.Time.Before(query.Since) {
+			entries = append(entries, entry)
+		}
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+func (m *MemoryHistory) Bounds(topic string) (uint64, uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	buffer, exists := m.topics[topic]
+	if !exists {
+		return 0, 0, nil
+	}
+	m.trim(buffer)
+	if len(buffer.entries) == 0 {
+		return 0, buffer.seq, nil
+	}
+	return buffer.entries[0].Seq, buffer.seq, nil
+}
+
+func (m *MemoryHistory) Close() error {
+	return nil
+}
+
+var _ HistoryStore = (*MemoryHistory)(nil)
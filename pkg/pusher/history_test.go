@@ -0,0 +1,71 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: history_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 13:30
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetHistoryZeroDisables(t *testing.T) {
+	h := &Hub{}
+	h.SetHistory(10, time.Minute)
+	if _, err := h.History("car", HistoryQuery{}); err != nil {
+		t.Fatalf("history enabled: %v", err)
+	}
+	h.SetHistory(0, time.Minute)
+	if _, err := h.History("car", HistoryQuery{}); errorCode(err) != CodeHistoryDisabled {
+		t.Fatalf("history error = %v, want CodeHistoryDisabled", err)
+	}
+}
+
+func TestHistoryMethodAuthorization(t *testing.T) {
+	hub := NewHub()
+	handler := &TypedHandler[int]{Topic: "HistoryRoom"}
+	hub.TopicRegister(handler)
+	defer hub.TopicUnRegister(handler)
+	hub.SetHistory(10, time.Minute)
+	hub.appendHistory(handler.Name(), NewData(handler.Name(), 1))
+	// 策略按订阅参数判断，参数缺失即拒绝
+	hub.SetSubscribePolicy(func(_ User, _ string, params json.RawMessage) error {
+		if string(params) != `{"vin":"v1"}` {
+			return errors.New("vin required")
+		}
+		return nil
+	})
+
+	subscribed := newStubClient("alice", nil)
+	subscribed.subs = []Subscription{{Topic: "historyroom", Params: json.RawMessage(`{"vin":"v1"}`)}}
+	tests := []struct {
+		name   string
+		client Client
+		code   ErrorCode
+	}{
+		{name: "subscribed", client: subscribed, code: CodeOK},
+		{name: "not subscribed", client: newStubClient("bob", nil), code: CodeTopicNotSubscribed},
+		{name: "params denied", client: newStubClient("carol", nil, "HistoryRoom"), code: CodeForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := hub.historyMethod(context.Background(), tt.client, HistoryParams{Topic: "historyRoom"})
+			if code := errorCode(err); code != tt.code {
+				t.Fatalf("code = %d, want %d (%v)", code, tt.code, err)
+			}
+			if tt.code != CodeOK {
+				return
+			}
+			if items, _ := body.([]HistoryItem); len(items) != 1 {
+				t.Fatalf("items = %+v, want one entry", body)
+			}
+		})
+	}
+}
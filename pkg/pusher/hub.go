@@ -10,7 +10,6 @@ package pusher
 
 import (
 	"encoding/json"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -26,49 +25,102 @@ type Reader interface {
 }
 
 type Hub struct {
-	mutex         sync.RWMutex
-	clients       map[Client]struct{}
-	handleRequest HandleRequest
-	readerMutex   sync.RWMutex
-	connectors    map[string]Reader
-	event         chan Data
+	mutex           sync.RWMutex
+	clients         map[Client]struct{}
+	directory       *directory
+	userID          UserIDFunc
+	handleRequest   HandleRequest
+	authenticator   Authenticator
+	subscribePolicy SubscribePolicy
+	methods         *methodRegistry
+	presence        *presence
+	guard           *guard
+	cluster         *cluster
+	historyMutex    sync.RWMutex
+	history         HistoryStore
+	sessionTTL      time.Duration
+	snapshotTimeout time.Duration
+	middlewares     *middlewares
+	codecs          *codecs
+	outbound        *outbound
+	deltas          *deltas
+	protocols       *protocols
+	pipeline        pipelineHolder
+	sessions        *sessions
+	readerMutex     sync.RWMutex
+	connectors      map[string]Reader
+	event           chan Data
 }
 
 func NewHub() *Hub {
 	hub := &Hub{
 		clients:    make(map[Client]struct{}),
+		directory:  newDirectory(),
+		userID:     DefaultUserID,
 		connectors: make(map[string]Reader),
 		event:      make(chan Data),
+		methods:    newMethodRegistry(),
+		presence:   newPresence(defaultPresenceDebounce),
+		guard:      newGuard(),
+		cluster:    newCluster(),
+		sessionTTL: defaultSessionTTL,
+		sessions:   newSessions(),
 	}
+	hub.snapshotTimeout = defaultSnapshotTimeout
+	hub.middlewares = newMiddlewares()
+	hub.codecs = newCodecs()
+	hub.outbound = newOutbound()
+	hub.deltas = newDeltas()
+	hub.protocols = &protocols{fallback: ProtocolV1}
+	hub.registerBuiltin("publish", TypedMethod(hub.publish))
+	hub.registerBuiltin("presence", TypedMethod(hub.presenceMethod))
+	hub.registerBuiltin("resume", TypedMethod(hub.resume))
+	hub.registerBuiltin("history", TypedMethod(hub.historyMethod))
+	hub.registerBuiltin("resync", TypedMethod(hub.resync))
+	hub.registerBuiltin("batch", TypedMethod(hub.batchMethod))
 	go hub.startReader()
 	go hub.Run()
 	return hub
 }
 
 func (h *Hub) ClientRegister(client Client) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 
 	if _, exists := h.clients[client]; !exists {
 		h.clients[client] = struct{}{}
+		h.directory.Add(client)
+		h.refreshUser(client)
 	}
 }
 
 func (h *Hub) ClientUnRegister(client Client) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 
 	if _, exists := h.clients[client]; exists {
+		h.saveSession(client)
 		client.Close()
 		delete(h.clients, client)
+		h.presence.LeaveAll(client)
+		h.directory.Remove(client)
+		h.guard.Detach(client)
 	}
 }
 
 func (h *Hub) TopicRegister(handler Handler) {
 	defaultTopicHandler.Register(handler)
+	if hook, ok := handler.(RegisterHook); ok {
+		hook.OnRegister()
+	}
+	h.acquire(handler)
 }
 
 func (h *Hub) TopicUnRegister(handler Handler) {
+	h.release(handler)
+	if hook, ok := handler.(RegisterHook); ok {
+		hook.OnUnRegister()
+	}
 	defaultTopicHandler.UnRegister(handler.Name())
 }
 
@@ -79,6 +131,10 @@ func (h *Hub) GetTopicHandler(name string) (Handler, bool) {
 
 // HandleRequest Handle client request
 func (h *Hub) HandleRequest(msg []byte, client Client) {
+	if !h.guard.AllowRequest(client) {
+		client.SendMessage(NewResponse("request", NewError(CodeRateLimited, "too many requests")))
+		return
+	}
 	if h.handleRequest == nil {
 		h.defaultHandleRequest(msg, client)
 		return
@@ -90,54 +146,202 @@ func (h *Hub) SetHandleRequest(handler HandleRequest) {
 	h.handleRequest = handler
 }
 
+// ClientRequest 客户端请求，ID 为可选的请求标识，会原样回填到对应的 Response 中
 type ClientRequest struct {
-	Method string   `json:"method"`
-	Topics []string `json:"topics" binding:"required"`
+	ID     string          `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Topics []string        `json:"topics,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// RegisterMethod 注册客户端可调用的方法，subscribe/unsubscribe 及 publish 等内置方法不可覆盖
+//
+//	hub.RegisterMethod("ackAlarm", func(ctx context.Context, client pusher.Client, params json.RawMessage) (interface{}, error) {
+//		return "ok", nil
+//	}, pusher.WithMethodTimeout(time.Second*3))
+func (h *Hub) RegisterMethod(name string, fn MethodFunc, opts ...MethodOption) {
+	if h.isBuiltin(name) {
+		logrus.Warnf("method %s is builtin, register ignored", name)
+		return
+	}
+	m := &method{
+		name:    name,
+		fn:      fn,
+		timeout: defaultMethodTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	h.methods.Register(m)
+}
+
+func (h *Hub) UnRegisterMethod(name string) {
+	if h.isBuiltin(name) {
+		return
+	}
+	h.methods.UnRegister(name)
+}
+
+func (h *Hub) registerBuiltin(name string, fn MethodFunc) {
+	h.methods.Register(&method{
+		name:    name,
+		fn:      fn,
+		timeout: defaultMethodTimeout,
+		builtin: true,
+	})
+}
+
+func (h *Hub) isBuiltin(name string) bool {
+	switch strings.ToLower(name) {
+	case "subscribe", "unsubscribe":
+		return true
+	}
+	m, exists := h.methods.Get(name)
+	return exists && m.builtin
 }
 
 func (h *Hub) defaultHandleRequest(msg []byte, client Client) {
 	var request ClientRequest
 	if err := json.Unmarshal(msg, &request); err != nil {
-		resp := NewResponse("register", err)
+		if h.guard.Malformed(client) {
+			client.Disconnect("too many malformed requests")
+			return
+		}
+		resp := NewResponse("register", NewError(CodeBadRequest, "%s", err.Error()))
 		client.SendMessage(resp)
 		return
 	}
 
+	switch strings.ToLower(request.Method) {
+	case "subscribe":
+		h.subscribe(client, &request)
+	case "unsubscribe":
+		h.unsubscribe(client, &request)
+	default:
+		m, exists := h.methods.Get(request.Method)
+		if !exists {
+			err := NewError(CodeMethodNotFound, "illegal method: %s", request.Method)
+			h.reply(client, &request, request.Method, err)
+			return
+		}
+		// 方法异步执行，避免阻塞客户端读取
+		go h.invoke(client, &request, m)
+	}
+}
+
+func (h *Hub) invoke(client Client, request *ClientRequest, m *method) {
+	body, err := m.call(client.Context(), client, request.Params)
+	if client.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		h.reply(client, request, request.Method, err)
+		return
+	}
+	h.reply(client, request, request.Method, body)
+}
+
+// SubscribeParams subscribe 请求参数
+//
+//	{"id":"1","method":"subscribe","topics":["car"],"params":{"presence":true}}
+type SubscribeParams struct {
+	// Presence 是否接收主题的用户加入/离开事件
+	Presence bool `json:"presence"`
+}
+
+// subscribe 订阅多个主题，所有主题的处理结果聚合在一个响应中返回
+func (h *Hub) subscribe(client Client, request *ClientRequest) {
 	if len(request.Topics) == 0 {
-		err := fmt.Errorf("topic is empty")
-		resp := NewResponse("register", err)
-		client.SendMessage(resp)
+		h.reply(client, request, "subscribe", NewError(CodeTopicEmpty, "topic is empty"))
+		return
+	}
+	if !h.guard.AllowSubscribe(client) {
+		h.reply(client, request, "subscribe", NewError(CodeRateLimited, "too many subscribe requests"))
 		return
 	}
+	var params SubscribeParams
+	if len(request.Params) > 0 {
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			h.reply(client, request, "subscribe", NewError(CodeInvalidParams, "invalid params: %s", err.Error()))
+			return
+		}
+	}
 
-	switch strings.ToLower(request.Method) {
-	case "subscribe":
-		for _, topic := range request.Topics {
-			handler, b := h.GetTopicHandler(topic)
-			if !b {
-				err := fmt.Errorf("topic not found: %s", topic)
-				resp := NewResponse("subscribe", err)
-				client.SendMessage(resp)
-				continue
-			}
+	results := make([]TopicResult, 0, len(request.Topics))
+	handlers := make([]Handler, 0, len(request.Topics))
+	available := h.guard.TopicsAvailable(client)
+	for _, topic := range request.Topics {
+		handler, b := h.GetTopicHandler(topic)
+		if !b {
+			err := NewError(CodeTopicNotFound, "topic not found: %s", topic)
+			results = append(results, NewTopicResult(topic, err))
+			continue
+		}
 
-			newHandler := handler.Clone()
-			if newHandler.Name() != handler.Name() {
-				err := fmt.Errorf("handler clone failed: %s", topic)
-				resp := NewResponse("subscribe", err)
-				client.SendMessage(resp)
-				continue
-			}
+		if err := h.authorize(client.User(), handler, request.Params); err != nil {
+			results = append(results, NewTopicResult(topic, err))
+			continue
+		}
 
-			client.AppendTopicHandler(newHandler)
+		if available >= 0 && len(handlers) >= available {
+			err := NewError(CodeTooManyTopics, "too many topics, topic: %s", topic)
+			results = append(results, NewTopicResult(topic, err))
+			continue
 		}
-	case "unsubscribe":
-		client.DeleteTopicHandlers(request.Topics)
-	default:
-		err := fmt.Errorf("illegal method: %s", request.Method)
-		resp := NewResponse(request.Method, err)
-		client.SendMessage(resp)
+
+		newHandler := handler.Clone()
+		if newHandler.Name() != handler.Name() {
+			err := NewError(CodeInternal, "handler clone failed: %s", topic)
+			results = append(results, NewTopicResult(topic, err))
+			continue
+		}
+
+		results = append(results, NewTopicResult(topic, nil))
+		handlers = append(handlers, newHandler)
+	}
+
+	h.replyResults(client, request, "subscribe", results)
+
+	// 先返回订阅结果，再加载各主题首次数据
+	for _, handler := range handlers {
+		client.AppendTopicHandler(handler, request.Params)
+		h.presence.Join(handler.Name(), client, h.UserID(client), params.Presence)
+	}
+}
+
+func (h *Hub) unsubscribe(client Client, request *ClientRequest) {
+	if len(request.Topics) == 0 {
+		h.reply(client, request, "unsubscribe", NewError(CodeTopicEmpty, "topic is empty"))
+		return
+	}
+
+	results := make([]TopicResult, 0, len(request.Topics))
+	for _, topic := range request.Topics {
+		err := client.DeleteTopicHandler(topic)
+		if err == nil {
+			h.presence.Leave(topic, client)
+		}
+		results = append(results, NewTopicResult(topic, err))
 	}
+	h.replyResults(client, request, "unsubscribe", results)
+}
+
+func (h *Hub) reply(client Client, request *ClientRequest, name string, data interface{}) {
+	resp := NewResponse(name, data)
+	resp.SetID(request.ID)
+	client.SendMessage(resp)
+}
+
+func (h *Hub) replyResults(client Client, request *ClientRequest, name string, results []TopicResult) {
+	resp := NewResponse(name, results)
+	resp.SetID(request.ID)
+	for _, result := range results {
+		if result.Code != CodeOK {
+			resp.SetCode(CodePartialFailure)
+			break
+		}
+	}
+	client.SendMessage(resp)
 }
 
 func (h *Hub) ReceiveChan() chan<- Data {
@@ -148,17 +352,32 @@ func (h *Hub) WriteEvent(event Data) {
 	h.event <- event
 }
 
+// Broadcast 将数据分发给各主题 Handler，指定了目标主题的数据只分发给该主题，
+// ClusterSharded 模式下仅主题属主节点执行 Handle
 func (h *Hub) Broadcast(msg Data) {
-	for topic, handler := range defaultTopicHandler.container {
+	target := strings.ToLower(Route(msg).Topic())
+	for topic, handler := range defaultTopicHandler.Handlers() {
+		if target != "" && target != topic {
+			continue
+		}
 		go func(topic string, handler Handler) {
-			handler.Handle(msg)
+			if h.Owns(topic) {
+				h.handle(handler, msg)
+			}
 			h.InvokeTopic(topic, msg)
 		}(topic, handler)
 	}
-
 }
 
+// InvokeTopic 推送给主题的订阅者，开启历史存储时先写入历史并分配主题序号
 func (h *Hub) InvokeTopic(topic string, msg Data) {
+	if seq := h.appendHistory(topic, msg); seq > 0 {
+		msg = &sequencedData{Data: msg, seq: seq}
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
 	for c := range h.clients {
 		go c.HandleMessage(topic, msg)
 	}
@@ -200,12 +419,18 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case msg := <-h.event:
-			h.Broadcast(msg)
+			for _, data := range h.process(msg) {
+				h.forwardEvent(data)
+				h.Broadcast(data)
+			}
 		case <-ticker.C:
-			if len(h.clients) == 0 {
+			h.mutex.RLock()
+			count := len(h.clients)
+			h.mutex.RUnlock()
+			if count == 0 {
 				continue
 			}
-			logrus.Infof("Current number of client connections: %d", len(h.clients))
+			logrus.Infof("Current number of client connections: %d", count)
 		}
 	}
 }
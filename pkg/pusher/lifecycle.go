@@ -0,0 +1,52 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: lifecycle
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 20:30
+ */
+
+package pusher
+
+import (
+	"context"
+	"strings"
+)
+
+// bind 为订阅的 Handler 副本设置派生自连接的 context
+func (c *client) bind(sub *subscription) {
+	sub.ctx, sub.cancel = context.WithCancel(c.ctx)
+	sub.handler.SetContext(sub.ctx, sub.cancel)
+}
+
+// replace 写入订阅并返回被替换的旧订阅，需持有 topicMutex
+func (c *client) replace(sub *subscription) *subscription {
+	name := strings.ToLower(sub.handler.Name())
+	replaced := c.topics[name]
+	c.topics[name] = sub
+	return replaced
+}
+
+// attach 订阅生效后调用 OnSubscribe
+func (c *client) attach(sub *subscription) {
+	hook, ok := sub.handler.(SubscribeHook)
+	if !ok {
+		return
+	}
+	defer recoverHandler(sub.handler, "OnSubscribe")
+	hook.OnSubscribe(sub.ctx, c.user, sub.params)
+}
+
+// detach 取消 Handler 副本的 context 后调用 OnUnsubscribe
+func (c *client) detach(sub *subscription) {
+	if sub == nil {
+		return
+	}
+	sub.cancel()
+	hook, ok := sub.handler.(UnsubscribeHook)
+	if !ok {
+		return
+	}
+	defer recoverHandler(sub.handler, "OnUnsubscribe")
+	hook.OnUnsubscribe(c.user)
+}
@@ -15,14 +15,17 @@ import (
 
 type Message interface {
 	Name() string
-	Marshal() []byte
+	Marshal() ([]byte, error)
 	First() bool
 }
 
 type message struct {
 	Code      int         `json:"code"`
+	ErrCode   ErrorCode   `json:"errcode,omitempty"`
 	Type      string      `json:"type"`
 	Topic     string      `json:"name"`
+	Seq       uint64      `json:"seq,omitempty"`
+	Version   uint64      `json:"version,omitempty"`
 	Body      interface{} `json:"body"`
 	Error     string      `json:"error,omitempty"`
 	Timestamp int64       `json:"timestamp"`
@@ -30,19 +33,20 @@ type message struct {
 }
 
 func NewMessage(name string, data interface{}, first bool) Message {
-	var code int
+	var code ErrorCode
 	var errMsg string
 	var body interface{}
 	err, ok := data.(error)
 	if ok {
-		code = 0
+		code = errorCode(err)
 		errMsg = err.Error()
 	} else {
-		code = 1
+		code = CodeOK
 		body = data
 	}
 	return &message{
-		Code:      code,
+		Code:      statusCode(code),
+		ErrCode:   code,
 		Type:      msgTypeData,
 		Topic:     name,
 		Body:      body,
@@ -56,11 +60,15 @@ func (msg *message) Name() string {
 	return msg.Topic
 }
 
+// SetSeq 设置消息对应数据的主题序号
+func (msg *message) SetSeq(seq uint64) {
+	msg.Seq = seq
+}
+
 func (msg *message) First() bool {
 	return msg.first
 }
 
-func (msg *message) Marshal() []byte {
-	marshal, _ := json.Marshal(msg)
-	return marshal
+func (msg *message) Marshal() ([]byte, error) {
+	return json.Marshal(msg)
 }
@@ -10,6 +10,9 @@ package pusher
 
 type metadata struct {
 	source string
+	topic  string
+	user   User
+	echo   bool
 }
 
 func (m *metadata) Source() string {
@@ -19,3 +22,27 @@ func (m *metadata) Source() string {
 func (m *metadata) SetSource(source string) {
 	m.source = source
 }
+
+func (m *metadata) Topic() string {
+	return m.topic
+}
+
+func (m *metadata) SetTopic(topic string) {
+	m.topic = topic
+}
+
+func (m *metadata) User() User {
+	return m.user
+}
+
+func (m *metadata) SetUser(user User) {
+	m.user = user
+}
+
+func (m *metadata) Echo() bool {
+	return m.echo
+}
+
+func (m *metadata) SetEcho(echo bool) {
+	m.echo = echo
+}
@@ -0,0 +1,133 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: method
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 10:05
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 方法默认超时时间
+const defaultMethodTimeout = 10 * time.Second
+
+// MethodFunc 客户端调用的 RPC 方法，返回值作为响应 body，返回错误时响应携带对应错误码
+type MethodFunc func(ctx context.Context, client Client, params json.RawMessage) (interface{}, error)
+
+// MethodAuthFunc 方法执行前的鉴权回调，返回错误则拒绝调用
+type MethodAuthFunc func(ctx context.Context, client Client) error
+
+type MethodOption func(m *method)
+
+// WithMethodAuth 为方法追加鉴权回调，按添加顺序依次执行
+func WithMethodAuth(auth MethodAuthFunc) MethodOption {
+	return func(m *method) {
+		m.auth = append(m.auth, auth)
+	}
+}
+
+// WithMethodTimeout 设置方法执行超时时间
+func WithMethodTimeout(timeout time.Duration) MethodOption {
+	return func(m *method) {
+		m.timeout = timeout
+	}
+}
+
+// TypedMethod 将请求参数解码为 P 类型后再调用 fn，解码失败返回 CodeInvalidParams
+func TypedMethod[P any](fn func(ctx context.Context, client Client, params P) (interface{}, error)) MethodFunc {
+	return func(ctx context.Context, client Client, raw json.RawMessage) (interface{}, error) {
+		var params P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, NewError(CodeInvalidParams, "invalid params: %s", err.Error())
+			}
+		}
+		return fn(ctx, client, params)
+	}
+}
+
+type method struct {
+	name    string
+	fn      MethodFunc
+	auth    []MethodAuthFunc
+	timeout time.Duration
+	builtin bool
+}
+
+type methodResult struct {
+	body interface{}
+	err  error
+}
+
+func (m *method) call(ctx context.Context, client Client, params json.RawMessage) (interface{}, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, m.timeout)
+	defer cancelFunc()
+
+	for _, auth := range m.auth {
+		if err := auth(ctx, client); err != nil {
+			return nil, forbidden(err)
+		}
+	}
+
+	done := make(chan methodResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.Errorf("method %s panic: %v", m.name, r)
+				done <- methodResult{err: NewError(CodeInternal, "method %s internal error", m.name)}
+			}
+		}()
+		body, err := m.fn(ctx, client, params)
+		done <- methodResult{body: body, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.body, result.err
+	case <-ctx.Done():
+		return nil, NewError(CodeTimeout, "method %s timeout", m.name)
+	}
+}
+
+type methodRegistry struct {
+	mutex     sync.RWMutex
+	container map[string]*method
+}
+
+func newMethodRegistry() *methodRegistry {
+	return &methodRegistry{
+		container: make(map[string]*method),
+	}
+}
+
+func (r *methodRegistry) Register(m *method) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.container[strings.ToLower(m.name)] = m
+}
+
+func (r *methodRegistry) UnRegister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.container, strings.ToLower(name))
+}
+
+func (r *methodRegistry) Get(name string) (*method, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	m, exists := r.container[strings.ToLower(name)]
+	return m, exists
+}
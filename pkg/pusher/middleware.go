@@ -0,0 +1,186 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: middleware
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 21:30
+ */
+
+package pusher
+
+import (
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HandleFunc 数据进入主题时的处理，默认调用 Handler.Handle
+type HandleFunc func(handler Handler, msg Data)
+
+// ViewFunc 按订阅者推送数据，默认调用 Handler.TopicView，首次加载时 msg 为 nil；
+// Handler 实现 Snapshotter 时首次数据同样经过中间件，此时 msg 为 LoadSnapshot 的返回值，user.First() 为 true
+type ViewFunc func(handler Handler, msg Data, user User)
+
+// Middleware 包装 Handle 的中间件，可用于日志、耗时统计等
+type Middleware func(next HandleFunc) HandleFunc
+
+// ViewMiddleware 包装 TopicView 的中间件，可用于按订阅者鉴权、数据脱敏等
+//
+//	hub.UseView(func(next pusher.ViewFunc) pusher.ViewFunc {
+//		return func(handler pusher.Handler, msg pusher.Data, user pusher.User) {
+//			if user.Claims()["role"] == "guest" {
+//				return
+//			}
+//			next(handler, msg, user)
+//		}
+//	})
+type ViewMiddleware func(next ViewFunc) ViewFunc
+
+// middlewares Hub 级及主题级中间件，Hub 级在外层，按注册顺序由外向内执行
+type middlewares struct {
+	mutex       sync.RWMutex
+	handle      []Middleware
+	view        []ViewMiddleware
+	topicHandle map[string][]Middleware
+	topicView   map[string][]ViewMiddleware
+	// 按主题缓存组合后的调用链，注册中间件时清空
+	handleChain map[string]HandleFunc
+	viewChain   map[string]ViewFunc
+}
+
+func newMiddlewares() *middlewares {
+	return &middlewares{
+		topicHandle: make(map[string][]Middleware),
+		topicView:   make(map[string][]ViewMiddleware),
+		handleChain: make(map[string]HandleFunc),
+		viewChain:   make(map[string]ViewFunc),
+	}
+}
+
+// Use 注册所有主题 Handle 的中间件
+func (h *Hub) Use(middleware ...Middleware) {
+	h.middlewares.mutex.Lock()
+	defer h.middlewares.mutex.Unlock()
+
+	h.middlewares.handle = append(h.middlewares.handle, middleware...)
+	h.middlewares.reset()
+}
+
+// UseView 注册所有主题 TopicView 的中间件
+func (h *Hub) UseView(middleware ...ViewMiddleware) {
+	h.middlewares.mutex.Lock()
+	defer h.middlewares.mutex.Unlock()
+
+	h.middlewares.view = append(h.middlewares.view, middleware...)
+	h.middlewares.reset()
+}
+
+// UseTopic 注册指定主题 Handle 的中间件，在 Hub 级中间件之后执行
+func (h *Hub) UseTopic(topic string, middleware ...Middleware) {
+	h.middlewares.mutex.Lock()
+	defer h.middlewares.mutex.Unlock()
+
+	topic = strings.ToLower(topic)
+	h.middlewares.topicHandle[topic] = append(h.middlewares.topicHandle[topic], middleware...)
+	h.middlewares.reset()
+}
+
+// UseTopicView 注册指定主题 TopicView 的中间件，在 Hub 级中间件之后执行
+func (h *Hub) UseTopicView(topic string, middleware ...ViewMiddleware) {
+	h.middlewares.mutex.Lock()
+	defer h.middlewares.mutex.Unlock()
+
+	topic = strings.ToLower(topic)
+	h.middlewares.topicView[topic] = append(h.middlewares.topicView[topic], middleware...)
+	h.middlewares.reset()
+}
+
+func (m *middlewares) reset() {
+	m.handleChain = make(map[string]HandleFunc)
+	m.viewChain = make(map[string]ViewFunc)
+}
+
+func (m *middlewares) handleFunc(topic string) HandleFunc {
+	m.mutex.RLock()
+	fn, exists := m.handleChain[topic]
+	m.mutex.RUnlock()
+	if exists {
+		return fn
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fn = func(handler Handler, msg Data) {
+		handler.Handle(msg)
+	}
+	chain := append(append([]Middleware{}, m.handle...), m.topicHandle[topic]...)
+	for i := len(chain) - 1; i >= 0; i-- {
+		fn = chain[i](fn)
+	}
+	m.handleChain[topic] = fn
+	return fn
+}
+
+func (m *middlewares) viewFunc(topic string) ViewFunc {
+	m.mutex.RLock()
+	fn, exists := m.viewChain[topic]
+	m.mutex.RUnlock()
+	if exists {
+		return fn
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fn = m.chainView(topic, func(handler Handler, msg Data, user User) {
+		handler.TopicView(msg, user)
+	})
+	m.viewChain[topic] = fn
+	return fn
+}
+
+// chainView 以 fn 为最内层组合 TopicView 中间件，需持有 mutex
+func (m *middlewares) chainView(topic string, fn ViewFunc) ViewFunc {
+	chain := append(append([]ViewMiddleware{}, m.view...), m.topicView[topic]...)
+	for i := len(chain) - 1; i >= 0; i-- {
+		fn = chain[i](fn)
+	}
+	return fn
+}
+
+// snapshotFunc 首次数据的调用链，最内层将 msg.Raw() 作为首次数据写入
+func (m *middlewares) snapshotFunc(topic string) ViewFunc {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.chainView(topic, func(handler Handler, msg Data, user User) {
+		user.Write(NewMessage(handler.Name(), msg.Raw(), true))
+	})
+}
+
+// handle 经过中间件调用 Handler.Handle，panic 时记录日志，不影响其他主题
+func (h *Hub) handle(handler Handler, msg Data) {
+	defer recoverHandler(handler, "Handle")
+	h.middlewares.handleFunc(strings.ToLower(handler.Name()))(handler, msg)
+}
+
+// view 经过中间件调用 Handler.TopicView，panic 时记录日志，不影响其他订阅者
+func (h *Hub) view(handler Handler, msg Data, user User) {
+	defer recoverHandler(handler, "TopicView")
+	h.middlewares.viewFunc(strings.ToLower(handler.Name()))(handler, msg, user)
+}
+
+// viewSnapshot 经过 TopicView 中间件推送 Snapshotter 的首次数据，使首次数据与实时数据经过相同的过滤
+func (h *Hub) viewSnapshot(handler Handler, body interface{}, user User) {
+	defer recoverHandler(handler, "Snapshot")
+	h.middlewares.snapshotFunc(strings.ToLower(handler.Name()))(handler, NewData(sourceSnapshot, body), user)
+}
+
+func recoverHandler(handler Handler, stage string) {
+	if r := recover(); r != nil {
+		logrus.Errorf("%s %s Panic: %v\n%s", handler.Name(), stage, r, debug.Stack())
+	}
+}
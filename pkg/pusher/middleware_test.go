@@ -0,0 +1,64 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: middleware_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 14:00
+ */
+
+package pusher
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSnapshotThroughViewMiddleware(t *testing.T) {
+	hub := &Hub{middlewares: newMiddlewares()}
+	hub.UseView(func(next ViewFunc) ViewFunc {
+		return func(handler Handler, msg Data, user User) {
+			if user.Claims()["role"] == "guest" {
+				return
+			}
+			next(handler, msg, user)
+		}
+	})
+	hub.UseTopicView("car", func(next ViewFunc) ViewFunc {
+		return func(handler Handler, msg Data, user User) {
+			if msg != nil {
+				msg = WithRaw(msg, map[string]string{"plate": "***"})
+			}
+			next(handler, msg, user)
+		}
+	})
+	handler := &TypedHandler[int]{Topic: "Car"}
+	snapshot := map[string]string{"plate": "A12345"}
+
+	messages := make(chan Message, 1)
+	member := &userInfo{user: "alice", claims: map[string]interface{}{"role": "member"}, msg: messages}
+	hub.viewSnapshot(handler, snapshot, member)
+	select {
+	case msg := <-messages:
+		raw, err := msg.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var envelope struct {
+			Body map[string]string `json:"body"`
+		}
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		if envelope.Body["plate"] != "***" || !msg.First() {
+			t.Fatalf("snapshot not redacted: %s", raw)
+		}
+	default:
+		t.Fatal("snapshot not delivered")
+	}
+
+	guest := &userInfo{user: "bob", claims: map[string]interface{}{"role": "guest"}, msg: messages}
+	hub.viewSnapshot(handler, snapshot, guest)
+	if len(messages) != 0 {
+		t.Fatal("snapshot delivered to rejected user")
+	}
+}
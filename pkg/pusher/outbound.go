@@ -0,0 +1,210 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: outbound
+ * @Version: 1.0.0
+ * @Date: 2026/10/22 10:00
+ */
+
+package pusher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/utils"
+)
+
+// defaultMinCompressSize 默认的压缩阈值
+const defaultMinCompressSize = 1024
+
+// chunkOverhead 分片消息除分片内容外的预留大小
+const chunkOverhead = 256
+
+// Compression permessage-deflate 压缩设置，仅对开启压缩的主题及方法生效
+type Compression struct {
+	// Level 压缩级别，取值 -2 ~ 9，0 使用默认级别
+	Level int
+	// MinSize 消息达到该大小时才压缩，默认 1024 字节
+	MinSize int
+	// Topics 开启压缩的主题名或方法名
+	Topics []string
+}
+
+// OversizePolicy 消息超出最大推送大小时的处理方式
+type OversizePolicy int
+
+const (
+	// OversizeReject 不推送该消息，改为推送同名的 CodeMessageTooLarge 错误
+	OversizeReject OversizePolicy = iota
+	// OversizeSplit 将编码后的消息拆分为多个 chunk 消息，客户端按序拼接后解码
+	OversizeSplit
+)
+
+type outbound struct {
+	mutex       sync.RWMutex
+	compression *Compression
+	topics      map[string]struct{}
+	maxSize     int
+	policy      OversizePolicy
+	batching    *Batching
+}
+
+func newOutbound() *outbound {
+	return &outbound{topics: make(map[string]struct{})}
+}
+
+// SetCompression 开启 permessage-deflate 协商，客户端支持时对 Topics 中达到 MinSize 的消息进行压缩
+//
+//	hub.SetCompression(pusher.Compression{MinSize: 4096, Topics: []string{"VehicleList", "history"}})
+func (h *Hub) SetCompression(compression Compression) {
+	if compression.MinSize <= 0 {
+		compression.MinSize = defaultMinCompressSize
+	}
+	topics := make(map[string]struct{}, len(compression.Topics))
+	for _, topic := range compression.Topics {
+		topics[topic] = struct{}{}
+	}
+
+	h.outbound.mutex.Lock()
+	defer h.outbound.mutex.Unlock()
+
+	h.outbound.compression = &compression
+	h.outbound.topics = topics
+}
+
+// SetMaxMessageSize 设置编码后单条消息的最大推送大小，0 表示不限制。
+// 开启 permessage-deflate 时按压缩前的大小计算
+func (h *Hub) SetMaxMessageSize(size int, policy OversizePolicy) {
+	h.outbound.mutex.Lock()
+	defer h.outbound.mutex.Unlock()
+
+	h.outbound.maxSize = size
+	h.outbound.policy = policy
+}
+
+// upgrader 开启压缩时允许协商 permessage-deflate
+func (o *outbound) upgrader(upgrader *websocket.Upgrader) *websocket.Upgrader {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	if o.compression == nil {
+		return upgrader
+	}
+	u := *upgrader
+	u.EnableCompression = true
+	return &u
+}
+
+func (o *outbound) setup(conn *websocket.Conn) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	conn.EnableWriteCompression(false)
+	if o.compression != nil && o.compression.Level != 0 {
+		if err := conn.SetCompressionLevel(o.compression.Level); err != nil {
+			logrus.Errorf("%s Set Compression Level Error: %s", conn.RemoteAddr().String(), err.Error())
+		}
+	}
+}
+
+// compress 消息是否需要压缩
+func (o *outbound) compress(name string, size int) bool {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	if o.compression == nil || size < o.compression.MinSize {
+		return false
+	}
+	_, exists := o.topics[name]
+	return exists
+}
+
+func (o *outbound) limit() (int, OversizePolicy) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	return o.maxSize, o.policy
+}
+
+// chunk 超出最大推送大小的消息分片，body 为编码后消息的 base64 片段
+type chunk struct {
+	Code      int       `json:"code"`
+	Type      string    `json:"type"`
+	Topic     string    `json:"name"`
+	Chunk     chunkInfo `json:"chunk"`
+	Body      string    `json:"body"`
+	Timestamp int64     `json:"timestamp"`
+}
+
+type chunkInfo struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Total int    `json:"total"`
+}
+
+func (msg *chunk) Name() string {
+	return msg.Topic
+}
+
+func (msg *chunk) First() bool {
+	return true
+}
+
+func (msg *chunk) Marshal() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// frames 按最大推送大小处理编码后的消息，返回需要依次发送的帧
+func (c *client) frames(message Message, data []byte) [][]byte {
+	maxSize, policy := c.hub.outbound.limit()
+	if maxSize <= 0 || len(data) <= maxSize {
+		return [][]byte{data}
+	}
+	// 仅 v2 客户端可以拼接分片，其他协议超出时总是拒绝
+	if policy == OversizeSplit && maxSize > 2*chunkOverhead && c.protocol == ProtocolV2 {
+		frames, err := c.split(message.Name(), data, maxSize)
+		if err == nil {
+			return frames
+		}
+		logrus.Errorf("%s Split Message Error: %s", message.Name(), err.Error())
+	}
+	logrus.Warnf("%s Message Size %d Exceeds %d, Rejected", message.Name(), len(data), maxSize)
+	rejected, err := c.encode(failedMessage(message, NewError(CodeMessageTooLarge, "message too large: %d bytes exceeds limit of %d bytes", len(data), maxSize)))
+	if err != nil {
+		return nil
+	}
+	return [][]byte{rejected}
+}
+
+// split 将编码后的消息拆分为 chunk 消息，客户端按 index 拼接 body 的 base64 解码结果后按连接编码解码
+func (c *client) split(name string, data []byte, maxSize int) ([][]byte, error) {
+	size := (maxSize - chunkOverhead) / 4 * 3
+	total := (len(data) + size - 1) / size
+	id := utils.RandString(16)
+	frames := make([][]byte, 0, total)
+	for index := 0; index < total; index++ {
+		end := (index + 1) * size
+		if end > len(data) {
+			end = len(data)
+		}
+		frame, err := c.encode(&chunk{
+			Code:      codeSuccess,
+			Type:      msgTypeChunk,
+			Topic:     name,
+			Chunk:     chunkInfo{ID: id, Index: index, Total: total},
+			Body:      base64.StdEncoding.EncodeToString(data[index*size : end]),
+			Timestamp: time.Now().Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
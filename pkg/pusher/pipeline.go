@@ -0,0 +1,53 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: pipeline
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 09:30
+ */
+
+package pusher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Pipeline 数据进入广播流程前的处理，如解码、校验、补全、拆分等。
+// 返回的数据依次广播，返回空表示丢弃，返回错误时丢弃该数据并记录日志。
+// 仅处理本节点写入的数据，其他节点转发来的数据已经处理过
+type Pipeline interface {
+	Process(ctx context.Context, data Data) ([]Data, error)
+}
+
+type pipelineHolder struct {
+	mutex    sync.RWMutex
+	pipeline Pipeline
+}
+
+// SetPipeline 设置数据处理管道，nil 表示不处理
+func (h *Hub) SetPipeline(pipeline Pipeline) {
+	h.pipeline.mutex.Lock()
+	defer h.pipeline.mutex.Unlock()
+
+	h.pipeline.pipeline = pipeline
+}
+
+// process 经过数据处理管道，未设置时原样返回
+func (h *Hub) process(data Data) []Data {
+	h.pipeline.mutex.RLock()
+	pipeline := h.pipeline.pipeline
+	h.pipeline.mutex.RUnlock()
+	if pipeline == nil {
+		return []Data{data}
+	}
+
+	results, err := pipeline.Process(context.Background(), data)
+	if err != nil {
+		logrus.Warnf("Pipeline Drop %s From %s: %s", data.ID(), data.Metadata().Source(), err.Error())
+		return nil
+	}
+	return results
+}
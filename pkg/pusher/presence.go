@@ -0,0 +1,372 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: presence
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 14:55
+ */
+
+package pusher
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 用户离开事件的默认防抖时间，短时间内重连不会产生 leave/join 事件
+const defaultPresenceDebounce = 5 * time.Second
+
+const (
+	PresenceJoin  = "join"
+	PresenceLeave = "leave"
+)
+
+// PresenceInfo 主题中在线用户信息
+type PresenceInfo struct {
+	UserID      string      `json:"userId"`
+	User        interface{} `json:"user"`
+	Connections int         `json:"connections"`
+	// Clients 用户在各节点上的连接 id
+	Clients []string `json:"clients,omitempty"`
+}
+
+// PresenceEvent 用户加入/离开主题事件
+type PresenceEvent struct {
+	Event string `json:"event"`
+	Topic string `json:"topic"`
+	// Client 触发事件的连接 id
+	Client string `json:"client,omitempty"`
+	PresenceInfo
+}
+
+type presenceEntry struct {
+	user  User
+	conns map[string]struct{}
+	leave *time.Timer
+}
+
+func (e *presenceEntry) info(userID string) PresenceInfo {
+	clients := make([]string, 0, len(e.conns))
+	for connID := range e.conns {
+		clients = append(clients, connID)
+	}
+	sort.Strings(clients)
+	return PresenceInfo{
+		UserID:      userID,
+		User:        e.user.User(),
+		Connections: len(e.conns),
+		Clients:     clients,
+	}
+}
+
+// presence 记录各主题订阅用户，匿名用户不参与统计；集群模式下合并其他节点同步的快照
+type presence struct {
+	mutex    sync.Mutex
+	debounce time.Duration
+	topics   map[string]map[string]*presenceEntry
+	watchers map[string]map[string]Client
+	joined   map[string]map[string]string
+	remote   map[string]map[string]map[string]PresenceInfo
+	onChange func()
+}
+
+func newPresence(debounce time.Duration) *presence {
+	return &presence{
+		debounce: debounce,
+		topics:   make(map[string]map[string]*presenceEntry),
+		watchers: make(map[string]map[string]Client),
+		joined:   make(map[string]map[string]string),
+		remote:   make(map[string]map[string]map[string]PresenceInfo),
+	}
+}
+
+// SetOnChange 本节点 presence 变更回调，用于集群同步
+func (p *presence) SetOnChange(onChange func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.onChange = onChange
+}
+
+func (p *presence) changed() {
+	if p.onChange != nil {
+		go p.onChange()
+	}
+}
+
+// present 用户是否在本节点或其他节点的主题中，调用方需持有锁
+func (p *presence) present(topic string, userID string) bool {
+	if _, exists := p.topics[topic][userID]; exists {
+		return true
+	}
+	return p.remotePresent(topic, userID)
+}
+
+func (p *presence) remotePresent(topic string, userID string) bool {
+	for _, topics := range p.remote {
+		if _, exists := topics[topic][userID]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot 本节点各主题在线用户
+func (p *presence) Snapshot() map[string]map[string]PresenceInfo {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	snapshot := make(map[string]map[string]PresenceInfo, len(p.topics))
+	for topic, users := range p.topics {
+		snapshot[topic] = make(map[string]PresenceInfo, len(users))
+		for userID, entry := range users {
+			snapshot[topic][userID] = entry.info(userID)
+		}
+	}
+	return snapshot
+}
+
+// ApplyRemote 用其他节点的快照替换该节点的 presence，集群范围内首次加入/完全离开的用户产生事件，
+// snapshot 为 nil 表示节点下线
+func (p *presence) ApplyRemote(node string, snapshot map[string]map[string]PresenceInfo) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	previous := p.remote[node]
+	changes := make(map[string]map[string]PresenceInfo)
+	collect := func(topics map[string]map[string]PresenceInfo) {
+		for topic, users := range topics {
+			for userID, info := range users {
+				if _, exists := changes[topic]; !exists {
+					changes[topic] = make(map[string]PresenceInfo)
+				}
+				changes[topic][userID] = info
+			}
+		}
+	}
+	collect(previous)
+	collect(snapshot)
+
+	before := make(map[string]map[string]bool)
+	for topic, users := range changes {
+		before[topic] = make(map[string]bool)
+		for userID := range users {
+			before[topic][userID] = p.present(topic, userID)
+		}
+	}
+	if snapshot == nil {
+		delete(p.remote, node)
+	} else {
+		p.remote[node] = snapshot
+	}
+	for topic, users := range changes {
+		for userID, info := range users {
+			after := p.present(topic, userID)
+			var client string
+			if len(info.Clients) > 0 {
+				client = info.Clients[0]
+			}
+			switch {
+			case !before[topic][userID] && after:
+				p.notify(topic, PresenceJoin, client, info)
+			case before[topic][userID] && !after:
+				info.Connections, info.Clients = 0, nil
+				p.notify(topic, PresenceLeave, client, info)
+			}
+		}
+	}
+}
+
+// Join 连接订阅主题，watch 为 true 时该连接接收主题的 presence 事件
+func (p *presence) Join(topic string, client Client, userID string, watch bool) {
+	topic = strings.ToLower(topic)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if watch {
+		if _, exists := p.watchers[topic]; !exists {
+			p.watchers[topic] = make(map[string]Client)
+		}
+		p.watchers[topic][client.ID()] = client
+	}
+	if userID == "" {
+		return
+	}
+	if _, exists := p.joined[client.ID()]; !exists {
+		p.joined[client.ID()] = make(map[string]string)
+	}
+	p.joined[client.ID()][topic] = userID
+
+	users, exists := p.topics[topic]
+	if !exists {
+		users = make(map[string]*presenceEntry)
+		p.topics[topic] = users
+	}
+	entry, exists := users[userID]
+	if !exists {
+		entry = &presenceEntry{user: client.User(), conns: make(map[string]struct{})}
+		users[userID] = entry
+	}
+	// 防抖期间重新加入，取消离开事件
+	if entry.leave != nil {
+		entry.leave.Stop()
+		entry.leave = nil
+	}
+	entry.conns[client.ID()] = struct{}{}
+	if !exists {
+		if !p.remotePresent(topic, userID) {
+			p.notify(topic, PresenceJoin, client.ID(), entry.info(userID))
+		}
+		p.changed()
+	}
+}
+
+// Leave 连接取消订阅主题
+func (p *presence) Leave(topic string, client Client) {
+	topic = strings.ToLower(topic)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.leave(topic, client)
+}
+
+// LeaveAll 连接断开，离开所有主题
+func (p *presence) LeaveAll(client Client) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for topic := range p.watchers {
+		delete(p.watchers[topic], client.ID())
+	}
+	for topic := range p.joined[client.ID()] {
+		p.leave(topic, client)
+	}
+	delete(p.joined, client.ID())
+}
+
+func (p *presence) leave(topic string, client Client) {
+	delete(p.watchers[topic], client.ID())
+	userID, exists := p.joined[client.ID()][topic]
+	if !exists {
+		return
+	}
+	delete(p.joined[client.ID()], topic)
+
+	entry, exists := p.topics[topic][userID]
+	if !exists {
+		return
+	}
+	delete(entry.conns, client.ID())
+	if len(entry.conns) > 0 || entry.leave != nil {
+		return
+	}
+	entry.leave = time.AfterFunc(p.debounce, func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		// 防抖期间用户已重新加入
+		if len(entry.conns) > 0 || p.topics[topic][userID] != entry {
+			return
+		}
+		delete(p.topics[topic], userID)
+		if len(p.topics[topic]) == 0 {
+			delete(p.topics, topic)
+		}
+		if !p.remotePresent(topic, userID) {
+			p.notify(topic, PresenceLeave, client.ID(), entry.info(userID))
+		}
+		p.changed()
+	})
+}
+
+// notify 推送 presence 事件给开启了 presence 的订阅者，client 为触发事件的连接，调用方需持有锁
+func (p *presence) notify(topic string, event string, client string, info PresenceInfo) {
+	msg := newPresenceMessage(topic, PresenceEvent{
+		Event:        event,
+		Topic:        topic,
+		Client:       client,
+		PresenceInfo: info,
+	})
+	for _, client := range p.watchers[topic] {
+		go client.SendMessage(msg)
+	}
+}
+
+// Users 主题当前在线用户，集群模式下连接数为所有节点之和，防抖期间的用户仍视为在线
+func (p *presence) Users(topic string) []PresenceInfo {
+	topic = strings.ToLower(topic)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	merged := make(map[string]PresenceInfo, len(p.topics[topic]))
+	for userID, entry := range p.topics[topic] {
+		merged[userID] = entry.info(userID)
+	}
+	for _, topics := range p.remote {
+		for userID, info := range topics[topic] {
+			if local, exists := merged[userID]; exists {
+				local.Connections += info.Connections
+				local.Clients = append(local.Clients, info.Clients...)
+				merged[userID] = local
+				continue
+			}
+			merged[userID] = info
+		}
+	}
+
+	users := make([]PresenceInfo, 0, len(merged))
+	for _, info := range merged {
+		users = append(users, info)
+	}
+	return users
+}
+
+func newPresenceMessage(topic string, event PresenceEvent) Message {
+	msg := NewMessage(topic, event, true).(*message)
+	msg.Type = msgTypePresence
+	return msg
+}
+
+// SetPresenceDebounce 设置用户离开事件的防抖时间
+func (h *Hub) SetPresenceDebounce(debounce time.Duration) {
+	h.presence.mutex.Lock()
+	defer h.presence.mutex.Unlock()
+
+	h.presence.debounce = debounce
+}
+
+// Presence 返回主题当前在线用户及其连接数
+func (h *Hub) Presence(topic string) []PresenceInfo {
+	return h.presence.Users(topic)
+}
+
+// PresenceParams presence 方法参数
+//
+//	{"id":"1","method":"presence","params":{"topic":"car"}}
+type PresenceParams struct {
+	Topic string `json:"topic"`
+}
+
+// presenceMethod 仅返回客户端已订阅且仍有权限的主题的在线用户
+func (h *Hub) presenceMethod(_ context.Context, client Client, params PresenceParams) (interface{}, error) {
+	if params.Topic == "" {
+		return nil, NewError(CodeTopicEmpty, "topic is empty")
+	}
+	handler, exists := h.GetTopicHandler(params.Topic)
+	if !exists {
+		return nil, NewError(CodeTopicNotFound, "topic not found: %s", params.Topic)
+	}
+	for _, sub := range client.Subscriptions() {
+		if !strings.EqualFold(sub.Topic, handler.Name()) {
+			continue
+		}
+		if err := h.authorize(client.User(), handler, sub.Params); err != nil {
+			return nil, err
+		}
+		return h.Presence(handler.Name()), nil
+	}
+	return nil, NewError(CodeTopicNotSubscribed, "%s topic not subscribed", params.Topic)
+}
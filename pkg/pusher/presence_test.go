@@ -0,0 +1,95 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: presence_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 10:20
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// stubClient 仅实现方法调用用到的 Client 方法，发送的消息记录在 sent 中
+type stubClient struct {
+	Client
+	id   string
+	user User
+	subs []Subscription
+	sent []Message
+}
+
+func (c *stubClient) ID() string {
+	return c.id
+}
+
+func (c *stubClient) User() User {
+	return c.user
+}
+
+func (c *stubClient) Subscriptions() []Subscription {
+	return c.subs
+}
+
+func (c *stubClient) SendMessage(message Message) {
+	c.sent = append(c.sent, message)
+}
+
+func newStubClient(id string, claims map[string]interface{}, topics ...string) *stubClient {
+	client := &stubClient{id: id, user: &userInfo{user: id, claims: claims}}
+	for _, topic := range topics {
+		client.subs = append(client.subs, Subscription{Topic: topic})
+	}
+	return client
+}
+
+func TestPresenceMethodAuthorization(t *testing.T) {
+	hub := NewHub()
+	handler := &TypedHandler[int]{Topic: "PresenceRoom"}
+	hub.TopicRegister(handler)
+	defer hub.TopicUnRegister(handler)
+	hub.SetSubscribePolicy(func(user User, topic string, _ json.RawMessage) error {
+		if user.Claims()["role"] != "member" {
+			return errors.New("members only")
+		}
+		return nil
+	})
+
+	member := map[string]interface{}{"role": "member"}
+	alice := newStubClient("alice", member, "presenceroom")
+	hub.presence.Join(handler.Name(), alice, "alice", false)
+
+	tests := []struct {
+		name   string
+		client Client
+		code   ErrorCode
+	}{
+		{name: "subscribed member", client: alice, code: CodeOK},
+		{name: "not subscribed", client: newStubClient("bob", member), code: CodeTopicNotSubscribed},
+		{name: "policy denied", client: newStubClient("carol", nil, "PresenceRoom"), code: CodeForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := hub.presenceMethod(context.Background(), tt.client, PresenceParams{Topic: "presenceRoom"})
+			if code := errorCode(err); code != tt.code {
+				t.Fatalf("code = %d, want %d (%v)", code, tt.code, err)
+			}
+			if tt.code != CodeOK {
+				return
+			}
+			users, _ := body.([]PresenceInfo)
+			if len(users) != 1 || users[0].UserID != "alice" || len(users[0].Clients) != 1 || users[0].Clients[0] != alice.ID() {
+				t.Fatalf("users = %+v, want alice", users)
+			}
+		})
+	}
+
+	if _, err := hub.presenceMethod(context.Background(), alice, PresenceParams{Topic: "missing"}); errorCode(err) != CodeTopicNotFound {
+		t.Fatalf("missing topic error = %v, want CodeTopicNotFound", err)
+	}
+}
@@ -0,0 +1,153 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: protocol
+ * @Version: 1.0.0
+ * @Date: 2026/10/23 09:30
+ */
+
+package pusher
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/flash520/pusher/pkg/codec"
+)
+
+// ProtocolVersion 客户端协议版本
+type ProtocolVersion int
+
+const (
+	// ProtocolV1 原有的消息格式，仅包含 code、type、name、body、error 及 timestamp，
+	// code 为 1 表示成功、0 表示失败，不支持增量同步、分片及批量推送
+	ProtocolV1 ProtocolVersion = 1
+	// ProtocolV2 在 v1 的基础上携带请求 id、主题序号及错误码 errcode，code 含义与 v1 相同
+	ProtocolV2 ProtocolVersion = 2
+	// ProtocolCentrifuge Centrifuge JSON 客户端协议，由 Hub.UpgradeCentrifuge 创建的连接使用
+	ProtocolCentrifuge ProtocolVersion = 100
+)
+
+// protocolQuery 协商协议版本的 url 参数
+const protocolQuery = "protocol"
+
+// v1 消息保留的字段
+var legacyFields = []string{"code", "type", "name", "body", "error", "timestamp"}
+
+type protocols struct {
+	mutex    sync.RWMutex
+	fallback ProtocolVersion
+}
+
+// SetDefaultProtocol 设置未协商版本的客户端使用的协议版本，默认 ProtocolV1
+func (h *Hub) SetDefaultProtocol(version ProtocolVersion) {
+	h.protocols.mutex.Lock()
+	defer h.protocols.mutex.Unlock()
+
+	h.protocols.fallback = version
+}
+
+func (p *protocols) getDefault() ProtocolVersion {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.fallback
+}
+
+// parseVersion 解析 1、2、v1、v2 形式的版本号
+func parseVersion(value string) (ProtocolVersion, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "v1":
+		return ProtocolV1, true
+	case "2", "v2":
+		return ProtocolV2, true
+	}
+	return 0, false
+}
+
+// parseSubprotocol 解析子协议，形式为 <编码>、<版本> 或 <版本>.<编码>，如 msgpack、v2、v2.msgpack+zstd
+func parseSubprotocol(protocol string) (ProtocolVersion, string) {
+	if version, ok := parseVersion(protocol); ok {
+		return version, codec.NameJSON
+	}
+	if i := strings.IndexByte(protocol, '.'); i > 0 {
+		if version, ok := parseVersion(protocol[:i]); ok {
+			return version, protocol[i+1:]
+		}
+	}
+	return 0, protocol
+}
+
+// negotiate 选择客户端提供的第一个可识别的版本或编码作为子协议，其余子协议（如 access_token）保持原有的协商
+func (h *Hub) negotiate(r *http.Request, upgrader *websocket.Upgrader) *websocket.Upgrader {
+	for _, protocol := range websocket.Subprotocols(r) {
+		_, name := parseSubprotocol(protocol)
+		if _, exists := h.codecs.get(name); !exists {
+			continue
+		}
+		u := *upgrader
+		u.Subprotocols = append([]string{protocol}, upgrader.Subprotocols...)
+		return &u
+	}
+	return upgrader
+}
+
+// protocol 连接协商的编码及协议版本，子协议未指定版本时依次使用 url 参数 protocol 及默认版本
+func (h *Hub) protocol(r *http.Request, conn *websocket.Conn) (Codec, ProtocolVersion) {
+	version, name := parseSubprotocol(strings.TrimSpace(conn.Subprotocol()))
+	if version == 0 {
+		var ok bool
+		if version, ok = parseVersion(r.URL.Query().Get(protocolQuery)); !ok {
+			version = h.protocols.getDefault()
+		}
+	}
+	item, exists := h.codecs.get(name)
+	if !exists {
+		item, _ = h.codecs.get(codec.NameJSON)
+	}
+	return item, version
+}
+
+// legacy 将 v2 消息转换为 v1 格式，移除 v1 没有的字段
+func legacy(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != '{' {
+		return data, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, key := range legacyFields {
+		raw, exists := fields[key]
+		if !exists {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + key + `":`)
+		buf.Write(raw)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *client) Protocol() ProtocolVersion {
+	return c.protocol
+}
+
+// marshal 按客户端协议版本序列化消息
+func (c *client) marshal(message Message) ([]byte, error) {
+	data, err := message.Marshal()
+	if err != nil || c.protocol != ProtocolV1 {
+		return data, err
+	}
+	return legacy(data)
+}
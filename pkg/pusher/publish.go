@@ -0,0 +1,55 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: publish
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 11:20
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// 客户端 publish 数据的来源标识
+const sourcePublish = "publish"
+
+// PublishParams publish 方法参数
+//
+//	{"id":"1","method":"publish","params":{"topic":"chat","data":{"text":"hi"},"echo":true}}
+type PublishParams struct {
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data"`
+	Echo  bool            `json:"echo"`
+}
+
+// publish 客户端向主题发布数据，经主题 Handler 鉴权校验后进入广播流程
+func (h *Hub) publish(ctx context.Context, client Client, params PublishParams) (interface{}, error) {
+	if params.Topic == "" {
+		return nil, NewError(CodeTopicEmpty, "topic is empty")
+	}
+	handler, exists := h.GetTopicHandler(params.Topic)
+	if !exists {
+		return nil, NewError(CodeTopicNotFound, "topic not found: %s", params.Topic)
+	}
+	publishable, ok := handler.(Publishable)
+	if !ok {
+		return nil, NewError(CodeForbidden, "topic %s does not accept publish", params.Topic)
+	}
+
+	payload, err := publishable.AuthorizePublish(ctx, client.User(), params.Data)
+	if err != nil {
+		return nil, forbidden(err)
+	}
+
+	data := NewData(sourcePublish, payload)
+	route := Route(data)
+	route.SetTopic(handler.Name())
+	route.SetUser(client.User())
+	route.SetEcho(params.Echo)
+	h.WriteEvent(data)
+
+	return map[string]string{"id": data.ID()}, nil
+}
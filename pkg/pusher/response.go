@@ -14,7 +14,9 @@ import (
 )
 
 type Response struct {
+	ID        string      `json:"id,omitempty"`
 	Code      int         `json:"code"`
+	ErrCode   ErrorCode   `json:"errcode,omitempty"`
 	Type      string      `json:"type"`
 	RespName  string      `json:"name"`
 	Body      interface{} `json:"body"`
@@ -24,19 +26,19 @@ type Response struct {
 
 func NewResponse(name string, data interface{}) *Response {
 	var errMsg string
-	var code int
-	var ok bool
+	var code ErrorCode
 	var body interface{}
 	err, ok := data.(error)
 	if !ok {
-		code = 1
+		code = CodeOK
 		body = data
 	} else {
-		code = 0
+		code = errorCode(err)
 		errMsg = err.Error()
 	}
 	return &Response{
-		Code:      code,
+		Code:      statusCode(code),
+		ErrCode:   code,
 		Type:      msgTypeMethod,
 		RespName:  name,
 		Body:      body,
@@ -45,6 +47,16 @@ func NewResponse(name string, data interface{}) *Response {
 	}
 }
 
+// SetID 回填客户端请求携带的 id，用于请求与响应的关联
+func (resp *Response) SetID(id string) {
+	resp.ID = id
+}
+
+func (resp *Response) SetCode(code ErrorCode) {
+	resp.Code = statusCode(code)
+	resp.ErrCode = code
+}
+
 func (resp *Response) SetName(name string) {
 	resp.RespName = name
 }
@@ -61,7 +73,6 @@ func (resp *Response) First() bool {
 	return true
 }
 
-func (resp *Response) Marshal() []byte {
-	marshal, _ := json.Marshal(resp)
-	return marshal
+func (resp *Response) Marshal() ([]byte, error) {
+	return json.Marshal(resp)
 }
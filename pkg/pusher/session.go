@@ -0,0 +1,137 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: session
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 16:40
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// session 断开连接后保留的订阅信息，供重连后恢复
+type session struct {
+	userID        string
+	subscriptions []Subscription
+	expire        *time.Timer
+}
+
+type sessions struct {
+	mutex sync.Mutex
+	items map[string]*session
+}
+
+func newSessions() *sessions {
+	return &sessions{
+		items: make(map[string]*session),
+	}
+}
+
+func (s *sessions) Save(token string, userID string, subscriptions []Subscription, ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item := &session{
+		userID:        userID,
+		subscriptions: subscriptions,
+	}
+	item.expire = time.AfterFunc(ttl, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if s.items[token] == item {
+			delete(s.items, token)
+		}
+	})
+	s.items[token] = item
+}
+
+// Take 取出会话，会话只能恢复一次
+func (s *sessions) Take(token string) (*session, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item, exists := s.items[token]
+	if !exists {
+		return nil, false
+	}
+	item.expire.Stop()
+	delete(s.items, token)
+	return item, true
+}
+
+// saveSession 连接断开时保留会话，未开启历史存储时不保留
+func (h *Hub) saveSession(client Client) {
+	if h.historyStore() == nil {
+		return
+	}
+	subscriptions := client.Subscriptions()
+	if len(subscriptions) == 0 {
+		return
+	}
+	h.historyMutex.RLock()
+	ttl := h.sessionTTL
+	h.historyMutex.RUnlock()
+	h.sessions.Save(client.Session(), h.UserID(client), subscriptions, ttl)
+}
+
+// ResumeParams resume 方法参数，topics 为各主题最后收到的消息序号
+//
+//	{"id":"1","method":"resume","params":{"session":"...","topics":{"car":128}}}
+type ResumeParams struct {
+	Session string            `json:"session"`
+	Topics  map[string]uint64 `json:"topics"`
+}
+
+// resume 恢复断开前的订阅并重放错过的消息，历史已不能覆盖的主题返回 CodeResyncRequired 并重新加载首次数据
+func (h *Hub) resume(_ context.Context, client Client, params ResumeParams) (interface{}, error) {
+	if params.Session == "" {
+		return nil, NewError(CodeInvalidParams, "session is empty")
+	}
+	previous, exists := h.sessions.Take(params.Session)
+	if !exists {
+		return nil, NewError(CodeSessionExpired, "session expired: %s", params.Session)
+	}
+	if previous.userID != h.UserID(client) {
+		return nil, NewError(CodeForbidden, "session belongs to another user")
+	}
+
+	last := make(map[string]uint64, len(params.Topics))
+	for topic, seq := range params.Topics {
+		last[strings.ToLower(topic)] = seq
+	}
+
+	results := make([]TopicResult, 0, len(previous.subscriptions))
+	for _, sub := range previous.subscriptions {
+		handler, exists := h.GetTopicHandler(sub.Topic)
+		if !exists {
+			results = append(results, NewTopicResult(sub.Topic, NewError(CodeTopicNotFound, "topic not found: %s", sub.Topic)))
+			continue
+		}
+		if err := h.authorize(client.User(), handler, sub.Params); err != nil {
+			results = append(results, NewTopicResult(sub.Topic, err))
+			continue
+		}
+		newHandler := handler.Clone()
+
+		seq, known := last[strings.ToLower(sub.Topic)]
+		if known && client.ResumeTopicHandler(newHandler, sub.Params, seq) {
+			results = append(results, NewTopicResult(sub.Topic, nil))
+		} else {
+			client.AppendTopicHandler(newHandler, sub.Params)
+			results = append(results, NewTopicResult(sub.Topic, NewError(CodeResyncRequired, "gap, resync required: %s", sub.Topic)))
+		}
+
+		var subscribeParams SubscribeParams
+		_ = json.Unmarshal(sub.Params, &subscribeParams)
+		h.presence.Join(newHandler.Name(), client, h.UserID(client), subscribeParams.Presence)
+	}
+	return results, nil
+}
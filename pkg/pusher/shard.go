@@ -0,0 +1,136 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: shard
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 14:30
+ */
+
+package pusher
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ClusterMode int
+
+const (
+	// ClusterBroadcast 每个节点都执行所有主题的 Handle
+	ClusterBroadcast ClusterMode = iota
+	// ClusterSharded 按一致性哈希为每个主题选出唯一的属主节点，仅属主节点执行 Handle，
+	// TopicView 仍在每个节点上为本地订阅者执行
+	ClusterSharded
+)
+
+// OwnershipAware 需要感知主题属主变更的 Handler 实现该接口，用于启动/停止只应在单个节点运行的任务，
+// 仅在 ClusterSharded 模式下回调。节点加入/离开后成员视图收敛前，可能短暂出现多个或没有属主
+type OwnershipAware interface {
+	OnAcquire()
+	OnRelease()
+}
+
+// SetClusterMode 设置集群模式下主题 Handle 的执行方式，默认 ClusterBroadcast，需在 SetBackplane 之前调用
+func (h *Hub) SetClusterMode(mode ClusterMode) {
+	h.cluster.mutex.Lock()
+	defer h.cluster.mutex.Unlock()
+
+	h.cluster.mode = mode
+}
+
+// Owns 本节点是否为主题的属主，非 ClusterSharded 模式或未开启集群时始终为 true
+func (h *Hub) Owns(topic string) bool {
+	h.cluster.mutex.RLock()
+	defer h.cluster.mutex.RUnlock()
+
+	return h.cluster.owns(topic)
+}
+
+func (c *cluster) owns(topic string) bool {
+	if c.mode != ClusterSharded || c.backplane == nil || c.ring == nil {
+		return true
+	}
+	return c.holds(topic)
+}
+
+// holds 分片模式下本节点是否持有主题
+func (c *cluster) holds(topic string) bool {
+	if c.mode != ClusterSharded || c.backplane == nil || c.ring == nil {
+		return false
+	}
+	return c.ring.Owner(strings.ToLower(topic)) == c.node
+}
+
+// rebalance 成员变更后重建哈希环，并通知属主发生变化的 Handler，members 为空表示退出集群
+func (h *Hub) rebalance(members []string) {
+	handlers := defaultTopicHandler.Handlers()
+
+	h.cluster.mutex.Lock()
+	before := make(map[string]bool, len(handlers))
+	for topic := range handlers {
+		before[topic] = h.cluster.holds(topic)
+	}
+	h.cluster.ring = newHashRing(members, defaultReplicas)
+	after := make(map[string]bool, len(handlers))
+	for topic := range handlers {
+		after[topic] = h.cluster.holds(topic)
+	}
+	h.cluster.mutex.Unlock()
+
+	for topic, handler := range handlers {
+		if before[topic] == after[topic] {
+			continue
+		}
+		aware, ok := handler.(OwnershipAware)
+		if after[topic] {
+			logrus.Infof("Topic %s Acquired By Node %s", topic, h.NodeID())
+			if ok {
+				aware.OnAcquire()
+			}
+		} else {
+			logrus.Infof("Topic %s Released By Node %s", topic, h.NodeID())
+			if ok {
+				aware.OnRelease()
+			}
+		}
+	}
+}
+
+// acquire 分片模式下新注册的主题由本节点持有时回调 OnAcquire
+func (h *Hub) acquire(handler Handler) {
+	if aware, ok := handler.(OwnershipAware); ok && h.holds(handler.Name()) {
+		aware.OnAcquire()
+	}
+}
+
+// release 分片模式下注销本节点持有的主题时回调 OnRelease
+func (h *Hub) release(handler Handler) {
+	if aware, ok := handler.(OwnershipAware); ok && h.holds(handler.Name()) {
+		aware.OnRelease()
+	}
+}
+
+func (h *Hub) holds(topic string) bool {
+	h.cluster.mutex.RLock()
+	defer h.cluster.mutex.RUnlock()
+
+	return h.cluster.holds(topic)
+}
+
+// EmitTopic 将 Handle 中生成的数据（如聚合结果）推送给主题的订阅者，
+// ClusterSharded 模式下同时转发给其他节点的订阅者，其他节点不会再执行 Handle
+func (h *Hub) EmitTopic(topic string, data Data) {
+	if _, ok := data.Metadata().(RouteMetadata); !ok {
+		data = WithRaw(data, data.Raw())
+	}
+	Route(data).SetTopic(topic)
+	h.cluster.mutex.RLock()
+	sharded := h.cluster.mode == ClusterSharded && h.cluster.backplane != nil
+	h.cluster.mutex.RUnlock()
+
+	if sharded {
+		h.forwardEvent(data)
+	}
+	h.InvokeTopic(topic, data)
+}
@@ -0,0 +1,129 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: snapshot
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 19:30
+ */
+
+package pusher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// 首次数据加载的默认超时时间
+	defaultSnapshotTimeout = 5 * time.Second
+	// 首次数据推送前单个订阅最多缓存的实时数据条数，超出时丢弃最旧的数据
+	maxPendingData = 1000
+)
+
+// Snapshotter 首次数据经过 TopicView 中间件时的数据来源
+const sourceSnapshot = "snapshot"
+
+// SetSnapshotTimeout 设置首次数据加载的超时时间，默认 5 秒
+func (h *Hub) SetSnapshotTimeout(timeout time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.snapshotTimeout = timeout
+}
+
+func (h *Hub) getSnapshotTimeout() time.Duration {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.snapshotTimeout
+}
+
+// snapshotUser 首次加载时传给 TopicView 的 User，First() 恒为 true，消息不经过推送队列直接发送
+type snapshotUser struct {
+	baseUser
+	client Client
+}
+
+func (u *snapshotUser) First() bool {
+	return true
+}
+
+func (u *snapshotUser) Write(msg Message) {
+	u.client.SendMessage(msg)
+}
+
+func (sub *subscription) buffer(msg Data) {
+	if len(sub.pending) >= maxPendingData {
+		sub.pending = sub.pending[1:]
+	}
+	sub.pending = append(sub.pending, msg)
+}
+
+// snapshot 推送首次数据后依次推送缓存的实时数据
+func (c *client) snapshot(sub *subscription) {
+	name := sub.handler.Name()
+	if snapshotter, ok := sub.handler.(Snapshotter); ok {
+		body, err := c.loadSnapshot(snapshotter, sub)
+		// 已取消订阅或连接已断开
+		if sub.ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil:
+			c.SendMessage(NewMessage(name, err, true))
+		case body != nil:
+			c.hub.viewSnapshot(sub.handler, body, &snapshotUser{baseUser: c.user, client: c})
+		}
+	} else {
+		c.hub.view(sub.handler, nil, &snapshotUser{baseUser: c.user, client: c})
+	}
+
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	c.topicMutex.RLock()
+	current := c.topics[strings.ToLower(name)]
+	c.topicMutex.RUnlock()
+	if current == sub {
+		for _, msg := range sub.pending {
+			c.deliver(sub, msg)
+		}
+	}
+	sub.pending = nil
+	sub.ready = true
+}
+
+func (c *client) loadSnapshot(snapshotter Snapshotter, sub *subscription) (body interface{}, err error) {
+	ctx, cancel := context.WithTimeout(sub.ctx, c.hub.getSnapshotTimeout())
+	defer cancel()
+
+	type result struct {
+		body interface{}
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.Errorf("%s Snapshot Panic: %v", sub.handler.Name(), r)
+				done <- result{err: NewError(CodeInternal, "internal error")}
+			}
+		}()
+		body, err := snapshotter.LoadSnapshot(ctx, c.user, sub.params)
+		done <- result{body: body, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.body, r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, NewError(CodeTimeout, "snapshot timeout")
+		}
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: typed
+ * @Version: 1.0.0
+ * @Date: 2026/10/20 18:30
+ */
+
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TypedHandler 泛型主题 Handler，数据先还原为 T，再按订阅者投影过滤后推送
+//
+//	hub.TopicRegister(&pusher.TypedHandler[int]{
+//		Topic:    "Car",
+//		Snapshot: func(ctx context.Context, user pusher.User) (int, error) { return 0, nil },
+//		View: func(ctx context.Context, number int, user pusher.User) (interface{}, bool) {
+//			return number, number%2 == 0
+//		},
+//	})
+type TypedHandler[T any] struct {
+	// Topic 主题名
+	Topic string
+	// Decode 将 Data 还原为 T，默认对 Raw() 做类型断言，失败时按 json 转换
+	Decode func(data Data) (T, error)
+	// Process 数据进入主题时调用，集群分片模式下仅主题所属节点调用
+	Process func(ctx context.Context, value T)
+	// Snapshot 订阅时的首次加载数据，为 nil 时不推送首次数据
+	Snapshot func(ctx context.Context, user User) (T, error)
+	// View 按订阅者投影并过滤数据，返回 false 时不推送，为 nil 时原样推送
+	View func(ctx context.Context, value T, user User) (interface{}, bool)
+	// Subscribed 订阅生效后调用，ctx 在取消订阅或连接断开时取消
+	Subscribed func(ctx context.Context, user User, params json.RawMessage)
+	// Unsubscribed 取消订阅或连接断开后调用
+	Unsubscribed func(user User)
+
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+}
+
+var (
+	_ Snapshotter     = (*TypedHandler[any])(nil)
+	_ SubscribeHook   = (*TypedHandler[any])(nil)
+	_ UnsubscribeHook = (*TypedHandler[any])(nil)
+)
+
+func (t *TypedHandler[T]) Name() string {
+	return t.Topic
+}
+
+func (t *TypedHandler[T]) context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+func (t *TypedHandler[T]) decode(data Data) (T, error) {
+	if t.Decode != nil {
+		return t.Decode(data)
+	}
+	if value, ok := data.Raw().(T); ok {
+		return value, nil
+	}
+	var value T
+	raw, ok := data.Raw().(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(data.Raw()); err != nil {
+			return value, err
+		}
+	}
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+func (t *TypedHandler[T]) Handle(data Data) {
+	if t.Process == nil {
+		return
+	}
+	value, err := t.decode(data)
+	if err != nil {
+		logrus.Errorf("%s Decode Data %s Error: %s", t.Topic, data.ID(), err.Error())
+		return
+	}
+	t.Process(t.context(), value)
+}
+
+// LoadSnapshot 首次加载数据，同样经过 View 投影过滤
+func (t *TypedHandler[T]) LoadSnapshot(ctx context.Context, user User, _ json.RawMessage) (interface{}, error) {
+	if t.Snapshot == nil {
+		return nil, nil
+	}
+	value, err := t.Snapshot(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	body, ok := t.view(ctx, value, user)
+	if !ok {
+		return nil, nil
+	}
+	return body, nil
+}
+
+func (t *TypedHandler[T]) TopicView(data Data, user User) {
+	ctx := t.context()
+	value, err := t.decode(data)
+	if err != nil {
+		logrus.Errorf("%s Decode Data %s Error: %s", t.Topic, data.ID(), err.Error())
+		return
+	}
+	if body, ok := t.view(ctx, value, user); ok {
+		user.Write(NewMessage(t.Topic, body, false))
+	}
+}
+
+func (t *TypedHandler[T]) view(ctx context.Context, value T, user User) (interface{}, bool) {
+	if t.View == nil {
+		return value, true
+	}
+	return t.View(ctx, value, user)
+}
+
+func (t *TypedHandler[T]) OnSubscribe(ctx context.Context, user User, params json.RawMessage) {
+	if t.Subscribed != nil {
+		t.Subscribed(ctx, user, params)
+	}
+}
+
+func (t *TypedHandler[T]) OnUnsubscribe(user User) {
+	if t.Unsubscribed != nil {
+		t.Unsubscribed(user)
+	}
+}
+
+func (t *TypedHandler[T]) SetContext(ctx context.Context, cancelFunc context.CancelFunc) {
+	t.ctx = ctx
+	t.cancelFunc = cancelFunc
+}
+
+func (t *TypedHandler[T]) Clone() Handler {
+	return &TypedHandler[T]{
+		Topic:        t.Topic,
+		Decode:       t.Decode,
+		Process:      t.Process,
+		Snapshot:     t.Snapshot,
+		View:         t.View,
+		Subscribed:   t.Subscribed,
+		Unsubscribed: t.Unsubscribed,
+	}
+}
@@ -0,0 +1,81 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: upgrade
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 20:10
+ */
+
+package pusher
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Upgrade 升级为 websocket 连接并创建客户端，依次进行封禁、连接数、鉴权及 Origin 校验，
+// 校验失败时以 401/403/429/503 拒绝，返回的客户端需调用 Run 启动
+func (h *Hub) Upgrade(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) (Client, error) {
+	return h.accept(w, r, upgrader, h.upgrade)
+}
+
+// accept 进行封禁及连接数校验后升级连接
+func (h *Hub) accept(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader,
+	upgrade func(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) (*client, string, error)) (Client, error) {
+	ip := h.guard.ClientIP(r)
+	if err := h.guard.Acquire(ip); err != nil {
+		http.Error(w, err.Error(), err.Status)
+		return nil, err
+	}
+
+	client, userID, err := upgrade(w, r, upgrader)
+	if err != nil {
+		h.guard.Release(ip)
+		return nil, err
+	}
+	h.guard.Attach(client, ip, userID)
+	return client, nil
+}
+
+// upgrade 鉴权并升级连接，返回已占用连接名额的用户标识
+func (h *Hub) upgrade(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) (*client, string, error) {
+	var userID string
+	var credentials *Credentials
+	if h.authenticator != nil {
+		var err error
+		credentials, err = h.authenticator.Authenticate(r)
+		if err != nil {
+			status := http.StatusUnauthorized
+			var authErr *AuthError
+			if errors.As(err, &authErr) {
+				status = authErr.Status
+			}
+			http.Error(w, err.Error(), status)
+			return nil, "", err
+		}
+
+		userID = h.credentialsUserID(credentials)
+		if err := h.guard.AcquireUser(userID); err != nil {
+			http.Error(w, err.Error(), err.Status)
+			return nil, "", err
+		}
+	}
+
+	conn, err := h.negotiate(r, h.outbound.upgrader(h.guard.upgrader(upgrader))).Upgrade(w, r, nil)
+	if err != nil {
+		h.guard.ReleaseUser(userID)
+		return nil, "", err
+	}
+
+	client := NewClient(h, conn)
+	client.codec, client.protocol = h.protocol(r, conn)
+	h.outbound.setup(conn)
+	if credentials != nil {
+		client.User().SetClaims(credentials.Claims)
+		client.User().SetUser(credentials.User)
+		client.SetExpiry(credentials.ExpiresAt)
+	}
+	return client, userID, nil
+}
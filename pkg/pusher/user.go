@@ -13,15 +13,21 @@ type User interface {
 	First() bool
 	Write(msg Message)
 	SetUser(user interface{})
+	// Claims 用户声明，由 Authenticator 写入
+	Claims() map[string]interface{}
+	SetClaims(claims map[string]interface{})
+	// Deprecated: 首次加载由 Snapshotter 或订阅时传入的独立 User 完成，不再修改该标记
 	SetFirst(first bool)
 	Close()
 }
 
 type userInfo struct {
-	user   interface{}
-	first  bool
-	closed bool
-	msg    chan<- Message
+	user     interface{}
+	claims   map[string]interface{}
+	first    bool
+	closed   bool
+	msg      chan<- Message
+	onChange func()
 }
 
 func (u *userInfo) User() interface{} {
@@ -30,6 +36,17 @@ func (u *userInfo) User() interface{} {
 
 func (u *userInfo) SetUser(user interface{}) {
 	u.user = user
+	if u.onChange != nil {
+		u.onChange()
+	}
+}
+
+func (u *userInfo) Claims() map[string]interface{} {
+	return u.claims
+}
+
+func (u *userInfo) SetClaims(claims map[string]interface{}) {
+	u.claims = claims
 }
 
 func (u *userInfo) First() bool {
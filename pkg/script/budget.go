@@ -0,0 +1,351 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: budget
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 10:00
+ */
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/pm"
+)
+
+// 各类对象按字节计入预算的估算值
+const (
+	stringCost  = 16
+	entryCost   = 40
+	tableCost   = 128
+	closureCost = 96
+)
+
+var exhausted = make(chan struct{})
+
+func init() {
+	close(exhausted)
+}
+
+// meter 单次执行的预算，作为 LState 的 context 使用。
+// 虚拟机执行每条指令前都会调用 Done，借此统计指令数；预算耗尽后 Done 始终返回已关闭的通道，
+// 脚本即使用 pcall 捕获了错误，下一条指令仍会中断
+type meter struct {
+	context.Context
+	steps    int
+	maxSteps int
+	bytes    int
+	maxBytes int
+	err      error
+}
+
+func newMeter(ctx context.Context, limits Limits) *meter {
+	return &meter{Context: ctx, maxSteps: limits.MaxInstructions, maxBytes: limits.MaxMemory}
+}
+
+func meterOf(L *lua.LState) *meter {
+	m, _ := L.Context().(*meter)
+	return m
+}
+
+func (m *meter) Done() <-chan struct{} {
+	if m.err == nil {
+		if m.steps++; m.steps > m.maxSteps {
+			m.err = fmt.Errorf("script exceeds %d instructions", m.maxSteps)
+		}
+	}
+	if m.err != nil {
+		return exhausted
+	}
+	return m.Context.Done()
+}
+
+func (m *meter) Err() error {
+	if m.err != nil {
+		return m.err
+	}
+	return m.Context.Err()
+}
+
+// alloc 在分配前计数，超出预算时中断脚本
+func (m *meter) alloc(L *lua.LState, size int) {
+	if m == nil {
+		return
+	}
+	if m.bytes += size; m.bytes > m.maxBytes && m.err == nil {
+		m.err = fmt.Errorf("script exceeds %d bytes of memory", m.maxBytes)
+	}
+	if m.err != nil {
+		L.RaiseError("%s", m.err.Error())
+	}
+}
+
+// helpers 改写后的脚本使用的辅助函数，顺序与 instrument 中的局部变量一致
+func helpers(L *lua.LState) []lua.LValue {
+	return []lua.LValue{L.NewFunction(luaConcat), L.NewFunction(luaTrack), L.NewFunction(luaSet)}
+}
+
+// luaConcat 替代 .. 运算符
+func luaConcat(L *lua.LState) int {
+	lhs, rhs := L.Get(1), L.Get(2)
+	if lua.LVCanConvToString(lhs) && lua.LVCanConvToString(rhs) {
+		a, b := lua.LVAsString(lhs), lua.LVAsString(rhs)
+		meterOf(L).alloc(L, stringCost+len(a)+len(b))
+		L.Push(lua.LString(a + b))
+		return 1
+	}
+	op := L.GetMetaField(lhs, "__concat")
+	if op == lua.LNil {
+		op = L.GetMetaField(rhs, "__concat")
+	}
+	if op.Type() != lua.LTFunction {
+		L.RaiseError("cannot perform concat operation between %s and %s", lhs.Type().String(), rhs.Type().String())
+	}
+	L.Push(op)
+	L.Push(lhs)
+	L.Push(rhs)
+	L.Call(2, 1)
+	return 1
+}
+
+// luaTrack 计数新构造的表和闭包
+func luaTrack(L *lua.LState) int {
+	value := L.Get(1)
+	switch v := value.(type) {
+	case *lua.LTable:
+		entries := 0
+		v.ForEach(func(lua.LValue, lua.LValue) { entries++ })
+		meterOf(L).alloc(L, tableCost+entries*entryCost)
+	case *lua.LFunction:
+		meterOf(L).alloc(L, closureCost)
+	}
+	L.Push(value)
+	return 1
+}
+
+// luaSet 替代下标赋值，新增表项时计数
+func luaSet(L *lua.LState) int {
+	obj, key, value := L.Get(1), L.Get(2), L.Get(3)
+	if tbl, ok := obj.(*lua.LTable); ok && value != lua.LNil && tbl.RawGet(key) == lua.LNil {
+		meterOf(L).alloc(L, entryCost)
+	}
+	L.SetTable(obj, key, value)
+	return 0
+}
+
+// meterLibs 替换库中会分配内存的函数，按结果或新增表项计数
+func meterLibs(L *lua.LState, limits Limits) {
+	if base, ok := L.Get(lua.GlobalsIndex).(*lua.LTable); ok {
+		rawset := builtin(base, "rawset")
+		base.RawSetString("rawset", L.NewFunction(func(L *lua.LState) int {
+			if tbl, ok := L.Get(1).(*lua.LTable); ok && L.Get(3) != lua.LNil && tbl.RawGet(L.Get(2)) == lua.LNil {
+				meterOf(L).alloc(L, entryCost)
+			}
+			return rawset(L)
+		}))
+	}
+	if tab, ok := L.GetGlobal(lua.TabLibName).(*lua.LTable); ok {
+		insert := builtin(tab, "insert")
+		tab.RawSetString("insert", L.NewFunction(func(L *lua.LState) int {
+			meterOf(L).alloc(L, entryCost)
+			return insert(L)
+		}))
+		tab.RawSetString("concat", L.NewFunction(counted(builtin(tab, "concat"))))
+	}
+	str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable)
+	if !ok {
+		return
+	}
+	for _, name := range []string{"char", "lower", "reverse", "sub", "upper"} {
+		str.RawSetString(name, L.NewFunction(counted(builtin(str, name))))
+	}
+	format := builtin(str, "format")
+	str.RawSetString("format", L.NewFunction(func(L *lua.LState) int {
+		args := make([]lua.LValue, 0, L.GetTop())
+		for i := 2; i <= L.GetTop(); i++ {
+			args = append(args, L.Get(i))
+		}
+		meterOf(L).alloc(L, stringCost+formatSize(L.CheckString(1), args))
+		return format(L)
+	}))
+	str.RawSetString("rep", L.NewFunction(func(L *lua.LState) int {
+		value, count := L.CheckString(1), L.CheckInt(2)
+		if count <= 0 {
+			L.Push(lua.LString(""))
+			return 1
+		}
+		if len(value)*count > limits.MaxStringLength {
+			L.RaiseError("string.rep result exceeds %d bytes", limits.MaxStringLength)
+		}
+		meterOf(L).alloc(L, stringCost+len(value)*count)
+		L.Push(lua.LString(strings.Repeat(value, count)))
+		return 1
+	}))
+	str.RawSetString("gsub", L.NewFunction(luaGsub))
+}
+
+func builtin(lib *lua.LTable, name string) lua.LGFunction {
+	return lib.RawGetString(name).(*lua.LFunction).GFunction
+}
+
+// counted 库函数返回后按结果字符串的长度计数，适用于结果不超过参数长度的函数
+func counted(fn lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		n := fn(L)
+		size := 0
+		for i := L.GetTop() - n + 1; i <= L.GetTop(); i++ {
+			if s, ok := L.Get(i).(lua.LString); ok {
+				size += stringCost + len(s)
+			}
+		}
+		meterOf(L).alloc(L, size)
+		return n
+	}
+}
+
+// formatSize 估算 string.format 结果长度的上限
+func formatSize(format string, args []lua.LValue) int {
+	size, longest := len(format), 0
+	for _, arg := range args {
+		if n := len(arg.String()); n > longest {
+			longest = n
+		}
+	}
+	// %[n]s 可以重复引用同一个参数，按最长的参数估算
+	indexed := strings.Contains(format, "[")
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i++; i < len(format) && format[i] == '%' {
+			continue
+		}
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0 {
+			i++
+		}
+		var width, precision int
+		width, i = formatNumber(format, i)
+		if i < len(format) && format[i] == '.' {
+			precision, i = formatNumber(format, i+1)
+		}
+		size += width + precision + 32
+		switch {
+		case indexed:
+			size += longest
+		case next < len(args):
+			size += len(args[next].String())
+			next++
+		}
+	}
+	return size
+}
+
+// formatNumber 读取格式中的宽度或精度，fmt 不接受超过 1e6 的值
+func formatNumber(format string, i int) (int, int) {
+	n := 0
+	for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+		if n = n*10 + int(format[i]-'0'); n > 1e6 {
+			n = 1e6
+		}
+	}
+	return n, i
+}
+
+// luaGsub 替代 string.gsub：库中的实现每次替换都复制整个字符串，且无法在中途计数。
+// 这里逐个匹配拼接结果，每段在写入前计数
+func luaGsub(L *lua.LState) int {
+	str := L.CheckString(1)
+	pattern := L.CheckString(2)
+	L.CheckTypes(3, lua.LTString, lua.LTTable, lua.LTFunction)
+	repl := L.Get(3)
+	limit := L.OptInt(4, -1)
+
+	matches, err := pm.Find(pattern, []byte(str), 0, limit)
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+	meterOf(L).alloc(L, stringCost+len(str))
+	var builder strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match.Capture(0), match.Capture(1)
+		value, ok := replacement(L, str, repl, match)
+		if !ok {
+			value = str[start:end]
+		}
+		builder.WriteString(str[last:start])
+		builder.WriteString(value)
+		last = end
+	}
+	builder.WriteString(str[last:])
+	L.Push(lua.LString(builder.String()))
+	L.Push(lua.LNumber(len(matches)))
+	return 2
+}
+
+// capture 返回第 idx 个捕获，没有捕获时 %1 等同于整个匹配
+func capture(L *lua.LState, str string, match *pm.MatchData, idx int) lua.LValue {
+	if idx >= match.CaptureLength() {
+		if idx != 2 {
+			L.RaiseError("invalid capture index")
+		}
+		idx = 0
+	}
+	if match.IsPosCapture(idx) {
+		return lua.LNumber(match.Capture(idx))
+	}
+	return lua.LString(str[match.Capture(idx):match.Capture(idx+1)])
+}
+
+// replacement 计算一次匹配的替换内容并计数，返回 false 时保留原文
+func replacement(L *lua.LState, str string, repl lua.LValue, match *pm.MatchData) (string, bool) {
+	var value lua.LValue
+	switch r := repl.(type) {
+	case lua.LString:
+		meterOf(L).alloc(L, len(r))
+		var builder strings.Builder
+		for i := 0; i < len(r); i++ {
+			if r[i] != '%' || i+1 == len(r) {
+				builder.WriteByte(r[i])
+				continue
+			}
+			i++
+			if r[i] < '0' || r[i] > '9' {
+				builder.WriteByte(r[i])
+				continue
+			}
+			part := capture(L, str, match, 2*int(r[i]-'0'))
+			meterOf(L).alloc(L, len(lua.LVAsString(part)))
+			builder.WriteString(lua.LVAsString(part))
+		}
+		return builder.String(), true
+	case *lua.LTable:
+		value = L.GetTable(r, capture(L, str, match, 2))
+	case *lua.LFunction:
+		L.Push(r)
+		n := 1
+		if match.CaptureLength() > 2 {
+			n = match.CaptureLength()/2 - 1
+		}
+		for i := 0; i < n; i++ {
+			L.Push(capture(L, str, match, 2*i+2))
+		}
+		L.Call(n, 1)
+		value = L.Get(-1)
+		L.Pop(1)
+	}
+	if !lua.LVIsFalse(value) {
+		if !lua.LVCanConvToString(value) {
+			L.RaiseError("invalid replacement value (a %s)", value.Type().String())
+		}
+		result := lua.LVAsString(value)
+		meterOf(L).alloc(L, len(result))
+		return result, true
+	}
+	return "", false
+}
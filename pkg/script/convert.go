@@ -0,0 +1,106 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: convert
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 14:20
+ */
+
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// toLua 转换为 lua 值，非 json 通用结构的数据先经 json 编解码
+func toLua(L *lua.LState, value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case uint64:
+		return lua.LNumber(v)
+	case uint32:
+		return lua.LNumber(v)
+	case map[string]interface{}:
+		table := L.CreateTable(0, len(v))
+		for key, item := range v {
+			table.RawSetString(key, toLua(L, item))
+		}
+		return table
+	case []interface{}:
+		table := L.CreateTable(len(v), 0)
+		for _, item := range v {
+			table.Append(toLua(L, item))
+		}
+		return table
+	case json.RawMessage:
+		var generic interface{}
+		if err := json.Unmarshal(v, &generic); err != nil {
+			return lua.LString(v)
+		}
+		return toLua(L, generic)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return lua.LString(fmt.Sprint(value))
+	}
+	return toLua(L, json.RawMessage(raw))
+}
+
+// fromLua 转换为 json 通用结构，连续整数键从 1 开始的 table 转换为数组
+func fromLua(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case *lua.LNilType:
+		return nil
+	case lua.LBool:
+		return bool(v)
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		f := float64(v)
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	case *lua.LTable:
+		if n := v.MaxN(); n > 0 && n == tableLen(v) {
+			items := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				items = append(items, fromLua(v.RawGetInt(i)))
+			}
+			return items
+		}
+		object := make(map[string]interface{})
+		v.ForEach(func(key, item lua.LValue) {
+			object[key.String()] = fromLua(item)
+		})
+		return object
+	}
+	return value.String()
+}
+
+func tableLen(table *lua.LTable) int {
+	n := 0
+	table.ForEach(func(lua.LValue, lua.LValue) {
+		n++
+	})
+	return n
+}
@@ -0,0 +1,112 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: handler
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 14:40
+ */
+
+package script
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+// Handler 由 lua 脚本实现的主题 Handler，脚本定义以下全局函数：
+//
+//	-- 必须，按订阅者投影过滤数据，返回 nil 时不推送
+//	function view(data, meta, user) return {speed = data.speed * 3.6} end
+//	-- 可选，数据进入主题时调用
+//	function handle(data, meta) end
+//	-- 可选，订阅时的首次加载数据，返回 nil 时不推送
+//	function snapshot(user, params) return nil end
+//
+// meta 为 {id, source, topic}，user 为 {id, user, claims}。
+// 同一脚本在多个虚拟机中并发执行，脚本不应依赖全局变量保存状态
+type Handler struct {
+	script     *Script
+	userID     pusher.UserIDFunc
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+}
+
+func (h *Handler) Name() string {
+	return h.script.topic
+}
+
+func (h *Handler) context() context.Context {
+	if h.ctx == nil {
+		return context.Background()
+	}
+	return h.ctx
+}
+
+func (h *Handler) Handle(data pusher.Data) {
+	program := h.script.current()
+	if _, err := program.call(h.context(), "handle", data.Raw(), meta(data)); err != nil {
+		logrus.Errorf("Script %s handle %s Error: %s", h.script.path, data.ID(), err.Error())
+	}
+}
+
+func (h *Handler) TopicView(data pusher.Data, user pusher.User) {
+	if data == nil {
+		return
+	}
+	program := h.script.current()
+	result, err := program.call(h.context(), "view", data.Raw(), meta(data), h.user(user))
+	if err != nil {
+		logrus.Errorf("Script %s view %s Error: %s", h.script.path, data.ID(), err.Error())
+		return
+	}
+	if result == nil {
+		return
+	}
+	user.Write(pusher.NewMessage(h.Name(), result, false))
+}
+
+func (h *Handler) LoadSnapshot(ctx context.Context, user pusher.User, params json.RawMessage) (interface{}, error) {
+	program := h.script.current()
+	var args interface{}
+	if len(params) > 0 {
+		args = params
+	}
+	return program.call(ctx, "snapshot", h.user(user), args)
+}
+
+func (h *Handler) user(user pusher.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     h.userID(user),
+		"user":   user.User(),
+		"claims": user.Claims(),
+	}
+}
+
+func meta(data pusher.Data) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     data.ID(),
+		"source": data.Metadata().Source(),
+		"topic":  pusher.Route(data).Topic(),
+	}
+}
+
+func (h *Handler) SetContext(ctx context.Context, cancelFunc context.CancelFunc) {
+	h.ctx = ctx
+	h.cancelFunc = cancelFunc
+}
+
+func (h *Handler) Clone() pusher.Handler {
+	return &Handler{
+		script: h.script,
+		userID: h.userID,
+	}
+}
+
+var (
+	_ pusher.Handler     = (*Handler)(nil)
+	_ pusher.Snapshotter = (*Handler)(nil)
+)
@@ -0,0 +1,149 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: loader
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 15:00
+ */
+
+package script
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/flash520/pusher/pkg/pusher"
+)
+
+const scriptExt = ".lua"
+
+type Options struct {
+	Limits Limits
+	// UserID 传给脚本的用户标识，默认 pusher.DefaultUserID
+	UserID pusher.UserIDFunc
+	// Interval 检查脚本变更的间隔，默认 2 秒
+	Interval time.Duration
+}
+
+// Loader 从目录加载 lua 脚本并注册为主题 Handler，主题名为不含扩展名的文件名。
+// 脚本修改后重新编译，已订阅的客户端随即使用新脚本；编译失败时保留旧版本；删除脚本时注销主题
+//
+//	loader := script.NewLoader(hub, "scripts", script.Options{})
+//	if err := loader.Load(); err != nil { ... }
+//	go loader.Watch(ctx)
+type Loader struct {
+	mutex   sync.Mutex
+	hub     *pusher.Hub
+	dir     string
+	options Options
+	scripts map[string]*Script
+}
+
+func NewLoader(hub *pusher.Hub, dir string, options Options) *Loader {
+	options.Limits.defaults()
+	if options.UserID == nil {
+		options.UserID = pusher.DefaultUserID
+	}
+	if options.Interval <= 0 {
+		options.Interval = 2 * time.Second
+	}
+	return &Loader{
+		hub:     hub,
+		dir:     dir,
+		options: options,
+		scripts: make(map[string]*Script),
+	}
+}
+
+// Load 加载目录中的全部脚本，返回首个编译错误，其余脚本仍会加载
+func (l *Loader) Load() error {
+	return l.scan()
+}
+
+// Watch 按间隔检查脚本新增、修改及删除，直到 ctx 取消
+func (l *Loader) Watch(ctx context.Context) {
+	ticker := time.NewTicker(l.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.scan(); err != nil {
+				logrus.Errorf("Reload Scripts Error: %s", err.Error())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (l *Loader) scan() error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var first error
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != scriptExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(l.dir, entry.Name())
+		seen[path] = struct{}{}
+		if err := l.load(path, info.ModTime()); err != nil && first == nil {
+			first = err
+		}
+	}
+	for path, script := range l.scripts {
+		if _, exists := seen[path]; exists {
+			continue
+		}
+		delete(l.scripts, path)
+		l.hub.TopicUnRegister(&Handler{script: script})
+		logrus.Infof("Script %s Removed, Topic %s UnRegistered", path, script.topic)
+	}
+	return first
+}
+
+func (l *Loader) load(path string, modTime time.Time) error {
+	script, exists := l.scripts[path]
+	if exists && modTime.Equal(script.modTime) {
+		return nil
+	}
+	program, err := compile(path, l.options.Limits)
+	if err != nil {
+		logrus.Errorf("Compile Script %s Error: %s", path, err.Error())
+		if exists {
+			// 保留旧版本，文件再次修改后重试
+			script.replace(script.current(), modTime)
+		}
+		return err
+	}
+	if exists {
+		script.replace(program, modTime)
+		logrus.Infof("Script %s Reloaded", path)
+		return nil
+	}
+
+	script = &Script{
+		topic: strings.TrimSuffix(filepath.Base(path), scriptExt),
+		path:  path,
+	}
+	script.replace(program, modTime)
+	l.scripts[path] = script
+	l.hub.TopicRegister(&Handler{script: script, userID: l.options.UserID})
+	logrus.Infof("Script %s Loaded, Topic %s Registered", path, script.topic)
+	return nil
+}
@@ -0,0 +1,238 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: rewrite
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 10:00
+ */
+
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/gopher-lua/ast"
+)
+
+// 改写时引入的标识符前缀，脚本中不允许使用
+const reservedPrefix = "__pusher_"
+
+const (
+	concatHelper = reservedPrefix + "concat"
+	trackHelper  = reservedPrefix + "track"
+	setHelper    = reservedPrefix + "set"
+)
+
+// instrument 改写语法树：字符串连接、表构造、闭包和下标赋值替换为计数的辅助函数调用。
+// 辅助函数作为顶层 chunk 的参数传入并存为局部变量，脚本代码放在内层函数中执行，
+// 既不能通过 ... 取到，也不能经全局表替换
+func instrument(chunk []ast.Stmt) ([]ast.Stmt, error) {
+	r := &rewriter{}
+	stmts := r.block(chunk)
+	if r.err != nil {
+		return nil, r.err
+	}
+	body := &ast.FunctionExpr{ParList: &ast.ParList{HasVargs: true, Names: []string{}}, Stmts: stmts}
+	return []ast.Stmt{
+		&ast.LocalAssignStmt{
+			Names: []string{concatHelper, trackHelper, setHelper},
+			Exprs: []ast.Expr{&ast.Comma3Expr{}},
+		},
+		&ast.FuncCallStmt{Expr: &ast.FuncCallExpr{Func: body, Args: []ast.Expr{}}},
+	}, nil
+}
+
+type rewriter struct {
+	temps int
+	err   error
+}
+
+// at 新节点沿用原节点的行号，出错时的位置信息保持不变
+func at[T ast.PositionHolder](node T, pos ast.PositionHolder) T {
+	node.SetLine(pos.Line())
+	node.SetLastLine(pos.LastLine())
+	return node
+}
+
+func (r *rewriter) name(name string) {
+	if r.err == nil && strings.HasPrefix(name, reservedPrefix) {
+		r.err = fmt.Errorf("identifier %s uses reserved prefix %s", name, reservedPrefix)
+	}
+}
+
+func (r *rewriter) temp() string {
+	r.temps++
+	return reservedPrefix + strconv.Itoa(r.temps)
+}
+
+func (r *rewriter) call(pos ast.PositionHolder, helper string, args ...ast.Expr) *ast.FuncCallExpr {
+	return at(&ast.FuncCallExpr{Func: at(&ast.IdentExpr{Value: helper}, pos), Args: args}, pos)
+}
+
+func (r *rewriter) set(pos ast.PositionHolder, args ...ast.Expr) ast.Stmt {
+	return at(&ast.FuncCallStmt{Expr: r.call(pos, setHelper, args...)}, pos)
+}
+
+func (r *rewriter) block(stmts []ast.Stmt) []ast.Stmt {
+	for i, stmt := range stmts {
+		stmts[i] = r.stmt(stmt)
+	}
+	return stmts
+}
+
+func (r *rewriter) exprs(exprs []ast.Expr) {
+	for i, expr := range exprs {
+		exprs[i] = r.expr(expr)
+	}
+}
+
+func (r *rewriter) function(fn *ast.FunctionExpr) {
+	for _, name := range fn.ParList.Names {
+		r.name(name)
+	}
+	fn.Stmts = r.block(fn.Stmts)
+}
+
+func (r *rewriter) stmt(stmt ast.Stmt) ast.Stmt {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		return r.assign(s)
+	case *ast.LocalAssignStmt:
+		for _, name := range s.Names {
+			r.name(name)
+		}
+		// local function f 需要保持函数表达式本身，编译器据此让 f 在函数体内可见
+		if fn, ok := s.Exprs[0].(*ast.FunctionExpr); ok && len(s.Names) == 1 && len(s.Exprs) == 1 {
+			r.function(fn)
+			return s
+		}
+		r.exprs(s.Exprs)
+	case *ast.FuncCallStmt:
+		s.Expr = r.expr(s.Expr)
+	case *ast.DoBlockStmt:
+		s.Stmts = r.block(s.Stmts)
+	case *ast.WhileStmt:
+		s.Condition = r.expr(s.Condition)
+		s.Stmts = r.block(s.Stmts)
+	case *ast.RepeatStmt:
+		s.Stmts = r.block(s.Stmts)
+		s.Condition = r.expr(s.Condition)
+	case *ast.IfStmt:
+		s.Condition = r.expr(s.Condition)
+		s.Then = r.block(s.Then)
+		s.Else = r.block(s.Else)
+	case *ast.NumberForStmt:
+		r.name(s.Name)
+		s.Init, s.Limit = r.expr(s.Init), r.expr(s.Limit)
+		if s.Step != nil {
+			s.Step = r.expr(s.Step)
+		}
+		s.Stmts = r.block(s.Stmts)
+	case *ast.GenericForStmt:
+		for _, name := range s.Names {
+			r.name(name)
+		}
+		r.exprs(s.Exprs)
+		s.Stmts = r.block(s.Stmts)
+	case *ast.FuncDefStmt:
+		s.Name.Func = r.expr(s.Name.Func)
+		if s.Name.Receiver != nil {
+			s.Name.Receiver = r.expr(s.Name.Receiver)
+		}
+		r.function(s.Func)
+	case *ast.ReturnStmt:
+		r.exprs(s.Exprs)
+	}
+	return stmt
+}
+
+// assign 下标赋值改为 set(obj, key, value)，新增的表项计入内存
+func (r *rewriter) assign(s *ast.AssignStmt) ast.Stmt {
+	r.exprs(s.Rhs)
+	indexed := false
+	for i, lhs := range s.Lhs {
+		s.Lhs[i] = r.expr(lhs)
+		if _, ok := lhs.(*ast.AttrGetExpr); ok {
+			indexed = true
+		}
+	}
+	if !indexed {
+		return s
+	}
+	if len(s.Lhs) == 1 {
+		target := s.Lhs[0].(*ast.AttrGetExpr)
+		return r.set(s, append([]ast.Expr{target.Object, target.Key}, s.Rhs...)...)
+	}
+
+	// 多重赋值先求出全部下标和右值，再逐个赋值
+	var names []string
+	var exprs []ast.Expr
+	for _, lhs := range s.Lhs {
+		if target, ok := lhs.(*ast.AttrGetExpr); ok {
+			names = append(names, r.temp(), r.temp())
+			exprs = append(exprs, target.Object, target.Key)
+		}
+	}
+	values := make([]string, len(s.Lhs))
+	for i := range values {
+		values[i] = r.temp()
+	}
+	ident := func(name string) ast.Expr {
+		return at(&ast.IdentExpr{Value: name}, s)
+	}
+	stmts := []ast.Stmt{at(&ast.LocalAssignStmt{Names: append(names, values...), Exprs: append(exprs, s.Rhs...)}, s)}
+	for i, lhs := range s.Lhs {
+		if _, ok := lhs.(*ast.AttrGetExpr); ok {
+			stmts = append(stmts, r.set(s, ident(names[0]), ident(names[1]), ident(values[i])))
+			names = names[2:]
+			continue
+		}
+		stmts = append(stmts, at(&ast.AssignStmt{Lhs: []ast.Expr{lhs}, Rhs: []ast.Expr{ident(values[i])}}, s))
+	}
+	return at(&ast.DoBlockStmt{Stmts: stmts}, s)
+}
+
+func (r *rewriter) expr(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.IdentExpr:
+		r.name(e.Value)
+	case *ast.AttrGetExpr:
+		e.Object, e.Key = r.expr(e.Object), r.expr(e.Key)
+	case *ast.TableExpr:
+		for _, field := range e.Fields {
+			if field.Key != nil {
+				field.Key = r.expr(field.Key)
+			}
+			field.Value = r.expr(field.Value)
+		}
+		return r.call(e, trackHelper, e)
+	case *ast.FuncCallExpr:
+		if e.Func != nil {
+			e.Func = r.expr(e.Func)
+		}
+		if e.Receiver != nil {
+			e.Receiver = r.expr(e.Receiver)
+		}
+		r.exprs(e.Args)
+	case *ast.LogicalOpExpr:
+		e.Lhs, e.Rhs = r.expr(e.Lhs), r.expr(e.Rhs)
+	case *ast.RelationalOpExpr:
+		e.Lhs, e.Rhs = r.expr(e.Lhs), r.expr(e.Rhs)
+	case *ast.ArithmeticOpExpr:
+		e.Lhs, e.Rhs = r.expr(e.Lhs), r.expr(e.Rhs)
+	case *ast.StringConcatOpExpr:
+		return r.call(e, concatHelper, r.expr(e.Lhs), r.expr(e.Rhs))
+	case *ast.UnaryMinusOpExpr:
+		e.Expr = r.expr(e.Expr)
+	case *ast.UnaryNotOpExpr:
+		e.Expr = r.expr(e.Expr)
+	case *ast.UnaryLenOpExpr:
+		e.Expr = r.expr(e.Expr)
+	case *ast.FunctionExpr:
+		r.function(e)
+		return r.call(e, trackHelper, e)
+	}
+	return expr
+}
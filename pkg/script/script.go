@@ -0,0 +1,254 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: script
+ * @Version: 1.0.0
+ * @Date: 2026/10/21 14:00
+ */
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Limits 脚本运行限制。lua 的对象分配在 Go 堆上，无法按字节精确统计，
+// 编译时把字符串连接、表构造和下标赋值改写为计数的辅助函数，库函数按结果大小计数，得到近似的内存预算
+type Limits struct {
+	// Timeout 单次调用的最长执行时间，默认 50ms，超时后中断脚本
+	Timeout time.Duration
+	// MaxInstructions 单次调用最多执行的虚拟机指令数，默认 1000000
+	MaxInstructions int
+	// MaxMemory 单次调用最多分配的字节数，默认 16MB；虚拟机累计分配超过该值后不再复用
+	MaxMemory int
+	// RegistryMaxSize 数据栈最大槽位数，默认 65536，限制栈上可持有的值数量
+	RegistryMaxSize int
+	// CallStackSize 最大调用深度，默认 128
+	CallStackSize int
+	// MaxStringLength string.rep 结果的最大长度，默认 1MB
+	MaxStringLength int
+	// PoolSize 每个脚本缓存的虚拟机数量，默认 8
+	PoolSize int
+}
+
+func (l *Limits) defaults() {
+	if l.Timeout <= 0 {
+		l.Timeout = 50 * time.Millisecond
+	}
+	if l.MaxInstructions <= 0 {
+		l.MaxInstructions = 1000000
+	}
+	if l.MaxMemory <= 0 {
+		l.MaxMemory = 16 << 20
+	}
+	if l.RegistryMaxSize <= 0 {
+		l.RegistryMaxSize = 65536
+	}
+	if l.CallStackSize <= 0 {
+		l.CallStackSize = 128
+	}
+	if l.MaxStringLength <= 0 {
+		l.MaxStringLength = 1 << 20
+	}
+	if l.PoolSize <= 0 {
+		l.PoolSize = 8
+	}
+}
+
+// 沙箱中移除的全局函数
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage", "getfenv", "setfenv"}
+
+// program 编译后的脚本及其虚拟机池，脚本重新加载时整体替换
+type program struct {
+	proto     *lua.FunctionProto
+	limits    Limits
+	pool      chan *machine
+	functions map[string]bool
+}
+
+// machine 池中的虚拟机，allocated 为其累计分配的字节数
+type machine struct {
+	L         *lua.LState
+	allocated int
+}
+
+func compile(filename string, limits Limits) (*program, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	chunk, err := parse.Parse(file, filename)
+	if err != nil {
+		return nil, err
+	}
+	if chunk, err = instrument(chunk); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	proto, err := lua.Compile(chunk, filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &program{
+		proto:     proto,
+		limits:    limits,
+		pool:      make(chan *machine, limits.PoolSize),
+		functions: make(map[string]bool),
+	}
+	// 执行一次顶层代码，检查脚本可以正常加载并记录定义了哪些函数
+	m, err := p.newMachine()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"view", "handle", "snapshot"} {
+		p.functions[name] = m.L.GetGlobal(name).Type() == lua.LTFunction
+	}
+	if !p.functions["view"] {
+		m.L.Close()
+		return nil, fmt.Errorf("%s: function view is not defined", filename)
+	}
+	p.put(m)
+	return p, nil
+}
+
+func (p *program) newMachine() (*machine, error) {
+	L := lua.NewState(lua.Options{
+		CallStackSize:       p.limits.CallStackSize,
+		RegistrySize:        1024,
+		RegistryMaxSize:     p.limits.RegistryMaxSize,
+		SkipOpenLibs:        true,
+		MinimizeStackMemory: true,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		parts := make([]string, 0, L.GetTop())
+		for i := 1; i <= L.GetTop(); i++ {
+			parts = append(parts, L.Get(i).String())
+		}
+		logrus.Infof("[lua] %s", strings.Join(parts, " "))
+		return 0
+	}))
+	meterLibs(L, p.limits)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.limits.Timeout)
+	defer cancel()
+	meter := newMeter(ctx, p.limits)
+	L.SetContext(meter)
+	L.Push(L.NewFunctionFromProto(p.proto))
+	helpers := helpers(L)
+	for _, helper := range helpers {
+		L.Push(helper)
+	}
+	if err := L.PCall(len(helpers), lua.MultRet, nil); err != nil {
+		L.Close()
+		return nil, err
+	}
+	L.RemoveContext()
+	L.SetTop(0)
+	return &machine{L: L, allocated: meter.bytes}, nil
+}
+
+func (p *program) get() (*machine, error) {
+	select {
+	case m := <-p.pool:
+		return m, nil
+	default:
+		return p.newMachine()
+	}
+}
+
+func (p *program) put(m *machine) {
+	select {
+	case p.pool <- m:
+	default:
+		m.L.Close()
+	}
+}
+
+// call 在限制下调用脚本函数，返回值转换为 json 通用结构
+func (p *program) call(ctx context.Context, name string, args ...interface{}) (result interface{}, err error) {
+	if !p.functions[name] {
+		return nil, nil
+	}
+	m, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	L := m.L
+	ctx, cancel := context.WithTimeout(ctx, p.limits.Timeout)
+	defer cancel()
+	meter := newMeter(ctx, p.limits)
+
+	// 出错的虚拟机状态不可信；累计分配超出预算的虚拟机可能在全局变量中持有大量数据，都直接丢弃
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("script panic: %v", r)
+		}
+		if m.allocated += meter.bytes; err != nil || m.allocated > p.limits.MaxMemory {
+			L.Close()
+			return
+		}
+		L.RemoveContext()
+		L.SetTop(0)
+		p.put(m)
+	}()
+
+	L.SetContext(meter)
+	values := make([]lua.LValue, 0, len(args))
+	for _, arg := range args {
+		values = append(values, toLua(L, arg))
+	}
+	if err = L.CallByParam(lua.P{Fn: L.GetGlobal(name), NRet: 1, Protect: true}, values...); err != nil {
+		return nil, err
+	}
+	return fromLua(L.Get(-1)), nil
+}
+
+// Script 目录中的单个脚本，主题名为不含扩展名的文件名
+type Script struct {
+	topic   string
+	path    string
+	mutex   sync.RWMutex
+	program *program
+	modTime time.Time
+}
+
+func (s *Script) current() *program {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.program
+}
+
+func (s *Script) replace(program *program, modTime time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.program = program
+	s.modTime = modTime
+}
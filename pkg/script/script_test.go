@@ -0,0 +1,141 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: script_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/25 11:00
+ */
+
+package script
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compileTest(t *testing.T, source string, limits Limits) (*program, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.lua")
+	if err := os.WriteFile(filename, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	limits.defaults()
+	return compile(filename, limits)
+}
+
+func TestCallLimits(t *testing.T) {
+	// 每个用例只保留一种限制生效，确认对应的限制能中断脚本
+	cpu := Limits{Timeout: time.Minute, MaxInstructions: 100000}
+	memory := Limits{Timeout: time.Minute, MaxInstructions: 1 << 30, MaxMemory: 1 << 20}
+	tests := []struct {
+		name   string
+		source string
+		limits Limits
+		err    string
+	}{
+		{name: "endless loop", source: `function view() while true do end end`, limits: cpu, err: "instructions"},
+		{name: "timeout", source: `function view() while true do end end`, limits: Limits{Timeout: 20 * time.Millisecond, MaxInstructions: 1 << 30}, err: "deadline"},
+		{name: "string doubling", source: `function view() local s = "x" while true do s = s .. s end end`, limits: memory, err: "memory"},
+		{name: "table growth", source: `function view() local t = {} while true do t[#t+1] = {} end end`, limits: memory, err: "memory"},
+		{name: "multiple assignment", source: `function view() local t, n = {}, 0 while true do n, t[n] = n + 1, n end end`, limits: memory, err: "memory"},
+		{name: "table insert", source: `function view() local t = {} while true do table.insert(t, 1) end end`, limits: memory, err: "memory"},
+		{name: "closures", source: `function view() local t = {} while true do t[#t+1] = function() end end end`, limits: memory, err: "memory"},
+		{name: "gsub", source: `function view() return (string.gsub(string.rep("x", 2000), ".", string.rep("y", 1000))) end`, limits: memory, err: "memory"},
+		{name: "format", source: `function view() return string.format("%1000000s%1000000s", "x", "y") end`, limits: memory, err: "memory"},
+		{name: "pcall", source: `function view() local s = "x" while true do pcall(function() s = s .. s end) end end`, limits: memory, err: "memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compileTest(t, tt.source, tt.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = p.call(context.Background(), "view"); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("call error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCallResult(t *testing.T) {
+	const source = `
+local function fib(n) if n < 2 then return n end return fib(n-1) + fib(n-2) end
+local mt = {__concat = function(a, b) return "meta" end}
+
+function view(data)
+	local t = {name = data.name .. "!", fib = fib(10)}
+	t.list = {}
+	for i = 1, 3 do t.list[#t.list+1] = i .. "" end
+	local i, a = 1, {}
+	i, a[i] = i + 1, 20
+	t.first = a[1]
+	t.meta = setmetatable({}, mt) .. "x"
+	t.gsub = string.gsub("hello world", "(o)", "[%1%%]")
+	t.upper = string.gsub("abc", "%w", string.upper)
+	t.lookup = string.gsub("$a $b", "%$(%w)", {a = "1"})
+	t.format = string.format("%5.1f|%s", 3.14159, "x")
+	rawset(t, "raw", true)
+	table.insert(t.list, "4")
+	t.joined = table.concat(t.list, ",")
+	return t
+end`
+	p, err := compileTest(t, source, Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.call(context.Background(), "view", map[string]interface{}{"name": "car"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":   "car!",
+		"fib":    int64(55),
+		"list":   []interface{}{"1", "2", "3", "4"},
+		"first":  int64(20),
+		"meta":   "meta",
+		"gsub":   "hell[o%] w[o%]rld",
+		"upper":  "ABC",
+		"lookup": "1 $b",
+		"format": "  3.1|x",
+		"raw":    true,
+		"joined": "1,2,3,4",
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("result = %#v, want %#v", result, want)
+	}
+}
+
+func TestReservedIdentifier(t *testing.T) {
+	if _, err := compileTest(t, `function view() return __pusher_set end`, Limits{}); err == nil {
+		t.Fatal("script using reserved identifier compiled")
+	}
+}
+
+func TestRecycleMachine(t *testing.T) {
+	// 全局变量在同一虚拟机的多次调用间累积，累计分配超出预算后虚拟机被替换
+	const source = `
+buf = {}
+function view() buf[#buf+1] = string.rep("x", 1000) return #buf end`
+	p, err := compileTest(t, source, Limits{MaxMemory: 10000, PoolSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var largest int64
+	for i := 0; i < 30; i++ {
+		result, err := p.call(context.Background(), "view")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := result.(int64); n > largest {
+			largest = n
+		}
+	}
+	if largest >= 30 {
+		t.Fatalf("machine reused after exceeding memory budget, buf grew to %d", largest)
+	}
+}
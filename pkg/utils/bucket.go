@@ -0,0 +1,50 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: bucket
+ * @Version: 1.0.0
+ * @Date: 2026/10/19 19:30
+ */
+
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket 令牌桶限流，rate 为每秒产生的令牌数，burst 为桶容量
+type TokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow 取一个令牌，桶中没有令牌时返回 false
+func (b *TokenBucket) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
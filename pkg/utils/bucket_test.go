@@ -0,0 +1,45 @@
+/**
+ * @Author: koulei
+ * @Description:
+ * @File: bucket_test
+ * @Version: 1.0.0
+ * @Date: 2026/10/24 11:20
+ */
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	bucket := NewTokenBucket(0.001, 3)
+	for i := 0; i < 3; i++ {
+		if !bucket.Allow() {
+			t.Fatalf("request %d rejected within burst", i)
+		}
+	}
+	if bucket.Allow() {
+		t.Fatal("request allowed beyond burst")
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	bucket := NewTokenBucket(50, 1)
+	if !bucket.Allow() {
+		t.Fatal("first request rejected")
+	}
+	if bucket.Allow() {
+		t.Fatal("request allowed before refill")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if !bucket.Allow() {
+		t.Fatal("request rejected after refill")
+	}
+	// 长时间空闲后令牌数不超过容量
+	bucket.last = time.Now().Add(-time.Hour)
+	if !bucket.Allow() || bucket.Allow() {
+		t.Fatal("tokens exceed burst after idle")
+	}
+}